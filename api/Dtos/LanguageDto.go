@@ -0,0 +1,15 @@
+package Dtos
+
+// LanguageDto describes one language variant a submission can select by id.
+type LanguageDto struct {
+	Id          int    `json:"id"`
+	Key         string `json:"key"`
+	Family      string `json:"family"`
+	Version     string `json:"version,omitempty"`
+	DisplayName string `json:"displayName"`
+	// TimeMultiplier scales the problem time limit for this language.
+	TimeMultiplier float64 `json:"timeMultiplier"`
+	// CompilerVersion and ImageDigest are filled in once the image has been probed.
+	CompilerVersion string `json:"compilerVersion,omitempty"`
+	ImageDigest     string `json:"imageDigest,omitempty"`
+}
@@ -1,6 +1,10 @@
 package Dtos
 
-import "judging-service/internal/models"
+import (
+	"judging-service/internal/models"
+	"judging-service/internal/policy"
+	"judging-service/internal/project"
+)
 
 type SubmissionQueueDto struct {
 	SubmissionId int                    `json:"submissionId"`
@@ -9,4 +13,97 @@ type SubmissionQueueDto struct {
 	MemoryLimit  int                    `json:"memoryLimit"`
 	TimeLimit    float32                `json:"timeLimit"`
 	InputTests   []models.TestCaseInput `json:"inputTests"`
+	// CompileFlags replace the language's default compile flags for this problem.
+	CompileFlags []string `json:"compileFlags,omitempty"`
+	// Diagnostics judges a practice submission in the language's diagnostic
+	// mode, e.g. with sanitizers, and reports why it failed at runtime.
+	Diagnostics bool `json:"diagnostics,omitempty"`
+	// RuntimeErrorOnNonZeroExit makes a program that exits with a non-zero
+	// code or is killed a runtime error for this problem, even when its
+	// output is correct.
+	RuntimeErrorOnNonZeroExit bool `json:"runtimeErrorOnNonZeroExit,omitempty"`
+	// AllowedModules and DeniedModules restrict what a Python submission may import.
+	AllowedModules []string `json:"allowedModules,omitempty"`
+	DeniedModules  []string `json:"deniedModules,omitempty"`
+	// Policy holds the problem's source rules, keyed by language family.
+	Policy map[string]policy.Rules `json:"policy,omitempty"`
+	// Drivers hold the problem's harness code, keyed by language family, for
+	// problems where the submission only implements a function.
+	Drivers map[string]models.Driver `json:"drivers,omitempty"`
+	// Graders hold the problem's grader sources and headers, keyed by
+	// language family, for IOI-style problems.
+	Graders map[string]models.Grader `json:"graders,omitempty"`
+	// Files and Archive send a multi-file project in place of Code: a map of
+	// relative paths to contents, or a zip or tar archive, base64 in JSON.
+	Files   map[string]string `json:"files,omitempty"`
+	Archive []byte            `json:"archive,omitempty"`
+	// EntryPoint is the project file the language's commands refer to as the
+	// source; it defaults to the language's source file, e.g. main.cpp.
+	EntryPoint string `json:"entryPoint,omitempty"`
+	// BuildCommand replaces the language's compile command for the project.
+	BuildCommand []string `json:"buildCommand,omitempty"`
+	// MaxFiles and MaxBytes limit the project; zero means the default limit.
+	MaxFiles int   `json:"maxFiles,omitempty"`
+	MaxBytes int64 `json:"maxBytes,omitempty"`
+	// InputFile and OutputFile, e.g. "input.txt" and "output.txt", replace
+	// stdin and stdout for problems with file-based I/O.
+	InputFile  string `json:"inputFile,omitempty"`
+	OutputFile string `json:"outputFile,omitempty"`
+}
+
+// Project reads the submission's multi-file project, or returns nil when it
+// sent code. The error is a *project.Error when the project is refused.
+func (s SubmissionQueueDto) Project() (*models.Project, error) {
+	if s.Files == nil && s.Archive == nil {
+		return nil, nil
+	}
+	if s.Code != "" || (s.Files != nil && s.Archive != nil) {
+		return nil, &project.Error{Reason: "send either code, files or an archive"}
+	}
+	limits := project.DefaultLimits
+	if s.MaxFiles > 0 {
+		limits.MaxFiles = s.MaxFiles
+	}
+	if s.MaxBytes > 0 {
+		limits.MaxBytes = s.MaxBytes
+	}
+	var files map[string]string
+	var err error
+	if s.Archive != nil {
+		files, err = project.Unpack(s.Archive, limits)
+	} else {
+		files, err = project.Load(s.Files, limits)
+	}
+	if err != nil {
+		return nil, err
+	}
+	entry := s.EntryPoint
+	if entry != "" {
+		if entry, err = project.CleanPath(entry); err != nil {
+			return nil, err
+		}
+	}
+	return &models.Project{Files: files, Entry: entry, BuildCommand: s.BuildCommand}, nil
+}
+
+// Options are the submission's choices on how its code is built and run,
+// given the family of its language.
+func (s SubmissionQueueDto) Options(family models.Language) models.SubmissionOptions {
+	options := models.SubmissionOptions{
+		CompileFlags:              s.CompileFlags,
+		Diagnostics:               s.Diagnostics,
+		RuntimeErrorOnNonZeroExit: s.RuntimeErrorOnNonZeroExit,
+		AllowedModules:            s.AllowedModules,
+		DeniedModules:             s.DeniedModules,
+	}
+	if driver, ok := s.Drivers[string(family)]; ok {
+		options.Driver = &driver
+	}
+	if grader, ok := s.Graders[string(family)]; ok {
+		options.Grader = &grader
+	}
+	if s.InputFile != "" || s.OutputFile != "" {
+		options.FileIO = &models.FileIO{Input: s.InputFile, Output: s.OutputFile}
+	}
+	return options
 }
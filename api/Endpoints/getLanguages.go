@@ -0,0 +1,40 @@
+package Endpoints
+
+import (
+	"encoding/json"
+	"judging-service/api/Dtos"
+	"judging-service/containers"
+	"net/http"
+)
+
+func GetLanguagesHandler(w http.ResponseWriter, r *http.Request, manger *containers.ContainersPoolManger) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	specs := manger.Languages.All()
+	result := make([]Dtos.LanguageDto, 0, len(specs))
+	for _, spec := range specs {
+		language := Dtos.LanguageDto{
+			Id:             spec.ID,
+			Key:            string(spec.Key),
+			Family:         string(spec.Family),
+			Version:        spec.Version,
+			DisplayName:    spec.DisplayName,
+			TimeMultiplier: spec.TimeMultiplier,
+		}
+		if probe, ok := manger.LanguageProbe(spec.ID); ok {
+			language.CompilerVersion = probe.Version
+			language.ImageDigest = probe.ImageDigest
+		}
+		result = append(result, language)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"languages":  result,
+		"totalCount": len(result),
+	})
+}
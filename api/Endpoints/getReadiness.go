@@ -0,0 +1,25 @@
+package Endpoints
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+)
+
+// ReadinessHandler answers 200 once the judge can run submissions and 503 before.
+func ReadinessHandler(w http.ResponseWriter, r *http.Request, ready *atomic.Bool) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	status := http.StatusOK
+	if !ready.Load() {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"ready": ready.Load(),
+	})
+}
@@ -11,11 +11,19 @@ import (
 )
 
 type JudgeSubmissionRequest struct {
-	SubmissionId int                       `json:"submissionId"`
-	IsErrorExist bool                      `json:"isErrorExist"`
-	FallingTest  *int                      `json:"fallingTest"`
-	Verdict      int                       `json:"verdict"`
-	Outputs      []JudgeProblemTestcaseDto `json:"outputs"`
+	SubmissionId   int                       `json:"submissionId"`
+	IsErrorExist   bool                      `json:"isErrorExist"`
+	FallingTest    *int                      `json:"fallingTest"`
+	Verdict        int                       `json:"verdict"`
+	Outputs        []JudgeProblemTestcaseDto `json:"outputs"`
+	Reason         string                    `json:"reason,omitempty"`
+	ReasonDetail   string                    `json:"reasonDetail,omitempty"`
+	CompileCommand string                    `json:"compileCommand,omitempty"`
+	CompileTimeMs  int64                     `json:"compileTimeMs,omitempty"`
+	// CompilationOutput is only sent when compilation failed.
+	CompilationOutput string `json:"compilationOutput,omitempty"`
+	// DiagnosticReport is only sent for diagnostic runs that failed at runtime.
+	DiagnosticReport string `json:"diagnosticReport,omitempty"`
 }
 
 type JudgeProblemTestcaseDto struct {
@@ -45,6 +53,13 @@ func SubmitJudgingResult(result models.JudgingResult, baseURL string) error {
 		FallingTest:  fallingTest,
 		Verdict:      result.Verdict,
 		Outputs:      outputs,
+
+		Reason:            string(result.Reason),
+		ReasonDetail:      result.ReasonDetail,
+		CompileCommand:    result.CompileCommand,
+		CompileTimeMs:     result.CompileTimeMs,
+		CompilationOutput: result.CompilationOutput,
+		DiagnosticReport:  result.DiagnosticReport,
 	}
 
 	jsonData, err := json.Marshal(request)
@@ -1,19 +1,80 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"github.com/gorilla/mux"
 	"judging-service/api/Endpoints"
 	api "judging-service/api/queue"
 	"judging-service/containers"
+	"judging-service/internal/cache"
+	"judging-service/internal/registry"
+	"judging-service/internal/sandbox"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync/atomic"
+	"syscall"
 )
 
 func main() {
+	// The native backend re-executes this binary as the sandbox init.
+	sandbox.RunInitIfRequested()
+
 	submissionQueue := api.NewSubmissionQueue()
-	var manger = containers.NewContainersPoolManger(10)
 
-	go api.ProcessQueueBackground(manger, submissionQueue)
+	// JUDGE_SANDBOX selects the backend: "docker" (default) or "native".
+	runtime, err := sandbox.NewRuntime(os.Getenv("JUDGE_SANDBOX"))
+	if err != nil {
+		log.Fatalf("failed to start sandbox backend: %v", err)
+	}
+	var manger = containers.NewContainersPoolMangerWithRuntime(10, runtime)
+	if path := os.Getenv("JUDGE_LANGUAGES"); path != "" {
+		languages, err := registry.Load(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		manger.Languages = languages
+	}
+	if cpuList := os.Getenv("JUDGE_PINNED_CORES"); cpuList != "" {
+		cores, err := containers.ParseCPUList(cpuList)
+		if err != nil {
+			log.Fatalf("invalid JUDGE_PINNED_CORES: %v", err)
+		}
+		if err := manger.EnableCorePinning(cores); err != nil {
+			log.Fatalf("failed to enable core pinning: %v", err)
+		}
+		log.Printf("Pinning pool containers to cores %v", cores)
+	}
+	if dir := os.Getenv("JUDGE_ARTIFACT_CACHE"); dir != "" {
+		sizeMB := 512
+		if value := os.Getenv("JUDGE_ARTIFACT_CACHE_MB"); value != "" {
+			if sizeMB, err = strconv.Atoi(value); err != nil {
+				log.Fatalf("invalid JUDGE_ARTIFACT_CACHE_MB: %v", err)
+			}
+		}
+		manger.ArtifactCache, err = cache.New(dir, int64(sizeMB)<<20)
+		if err != nil {
+			log.Fatalf("failed to open artifact cache: %v", err)
+		}
+		log.Printf("Caching compiled artifacts in %s, up to %d MB", dir, sizeMB)
+	}
+
+	// Submissions are queued right away but only judged once every image is
+	// pulled and verified; /api/ready reports when that point is reached.
+	var ready atomic.Bool
+	go func() {
+		if err := manger.PrepareImages(context.Background()); err != nil {
+			log.Fatalf("failed to prepare language images: %v", err)
+		}
+		ready.Store(true)
+		log.Println("All language images are ready")
+		go api.ProcessQueueBackground(manger, submissionQueue)
+		// Compiler versions and image digests for /api/languages.
+		manger.ProbeLanguages(context.Background())
+	}()
 
 	r := mux.NewRouter()
 	r.HandleFunc("/api/submission", func(w http.ResponseWriter, r *http.Request) {
@@ -24,6 +85,29 @@ func main() {
 		Endpoints.GetAllSubmissionsHandler(w, r, submissionQueue)
 	}).Methods("GET")
 	//
+	r.HandleFunc("/api/ready", func(w http.ResponseWriter, r *http.Request) {
+		Endpoints.ReadinessHandler(w, r, &ready)
+	}).Methods("GET")
+	//
+	r.HandleFunc("/api/languages", func(w http.ResponseWriter, r *http.Request) {
+		Endpoints.GetLanguagesHandler(w, r, manger)
+	}).Methods("GET")
+	//
+	server := &http.Server{Addr: ":8080", Handler: r}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-stop
+		log.Println("Shutting down")
+		_ = server.Shutdown(context.Background())
+	}()
+
 	log.Println("Server starting on :8080")
-	log.Fatal(http.ListenAndServe(":8080", r))
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
+	if err := manger.Close(); err != nil {
+		log.Printf("failed to close container pool: %v", err)
+	}
 }
@@ -5,8 +5,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"judging-service/api/Dtos"
 	"judging-service/containers"
 	"judging-service/internal/models"
+	"judging-service/internal/policy"
 	"judging-service/internal/processor"
 	"log"
 	"net/http"
@@ -20,6 +22,27 @@ type SubmissionRequest struct {
 	MemoryLimit  int                    `json:"memoryLimit"`
 	TimeLimit    float32                `json:"timeLimit"`
 	InputTests   []models.TestCaseInput `json:"inputTests"`
+	CompileFlags []string               `json:"compileFlags,omitempty"`
+	Diagnostics  bool                   `json:"diagnostics,omitempty"`
+	// AllowedModules and DeniedModules restrict Python imports.
+	AllowedModules []string `json:"allowedModules,omitempty"`
+	DeniedModules  []string `json:"deniedModules,omitempty"`
+	// Policy holds source rules per language family.
+	Policy map[string]policy.Rules `json:"policy,omitempty"`
+	// Drivers hold harness code per language family.
+	Drivers map[string]models.Driver `json:"drivers,omitempty"`
+	// Graders hold grader sources and headers per language family.
+	Graders map[string]models.Grader `json:"graders,omitempty"`
+	// Files or Archive send a multi-file project in place of Code.
+	Files        map[string]string `json:"files,omitempty"`
+	Archive      []byte            `json:"archive,omitempty"`
+	EntryPoint   string            `json:"entryPoint,omitempty"`
+	BuildCommand []string          `json:"buildCommand,omitempty"`
+	MaxFiles     int               `json:"maxFiles,omitempty"`
+	MaxBytes     int64             `json:"maxBytes,omitempty"`
+	// InputFile and OutputFile replace stdin and stdout.
+	InputFile  string `json:"inputFile,omitempty"`
+	OutputFile string `json:"outputFile,omitempty"`
 }
 
 type JudgmentResult struct {
@@ -33,14 +56,16 @@ type JudgmentResult struct {
 type JudgeService struct {
 	baseURL string
 	client  *http.Client
+	manger  *containers.ContainersPoolManger
 }
 
-func NewJudgeService(baseURL string) *JudgeService {
+func NewJudgeService(baseURL string, manger *containers.ContainersPoolManger) *JudgeService {
 	return &JudgeService{
 		baseURL: baseURL,
 		client: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		manger: manger,
 	}
 }
 
@@ -104,28 +129,44 @@ func (js *JudgeService) processSubmissions() {
 
 	for _, submission := range submissions {
 		log.Printf("Processing submission %d", submission.SubmissionId)
-		var manger = containers.NewContainersPoolManger(10)
-		var lang string
-		if submission.Language == 1 {
-			lang = string(models.Cpp)
-		} else if submission.Language == 0 {
-			lang = string(models.Python)
+		result, err := processor.RunCodeWithTestcases(js.manger, Dtos.SubmissionQueueDto{
+			SubmissionId:   submission.SubmissionId,
+			Code:           submission.Code,
+			Language:       submission.Language,
+			MemoryLimit:    submission.MemoryLimit,
+			TimeLimit:      submission.TimeLimit,
+			InputTests:     submission.InputTests,
+			CompileFlags:   submission.CompileFlags,
+			Diagnostics:    submission.Diagnostics,
+			AllowedModules: submission.AllowedModules,
+			DeniedModules:  submission.DeniedModules,
+			Policy:         submission.Policy,
+			Drivers:        submission.Drivers,
+			Graders:        submission.Graders,
+			Files:          submission.Files,
+			Archive:        submission.Archive,
+			EntryPoint:     submission.EntryPoint,
+			BuildCommand:   submission.BuildCommand,
+			MaxFiles:       submission.MaxFiles,
+			MaxBytes:       submission.MaxBytes,
+			InputFile:      submission.InputFile,
+			OutputFile:     submission.OutputFile,
+		})
+		if err != nil {
+			log.Printf("Submission %d failed: %v", submission.SubmissionId, err)
+		}
+		for _, output := range result.Outputs {
+			log.Printf("Testcase %d: %s", output.TestCaseId, output.Output)
 		}
-		var output []models.TestCaseOutput
-		output, _ = processor.RunCodeWithTestcases(
-			manger,
-			submission.Code,
-			submission.InputTests,
-			lang,
-			models.ResourceLimit{MemoryLimitInMB: submission.MemoryLimit, TimeLimitInSeconds: submission.TimeLimit})
-		log.Printf(output[0].Output)
 
 		log.Printf("Successfully processed submission %d", submission.SubmissionId)
 	}
 }
 
 func main() {
-	judgeService := NewJudgeService("http://localhost:5129")
+	manger := containers.NewContainersPoolManger(10)
+	defer manger.Close()
+	judgeService := NewJudgeService("http://localhost:5129", manger)
 
 	log.Println("Judge microservice started")
 
@@ -0,0 +1,221 @@
+// Command judgectl maintains the judge's language images.
+//
+//	judgectl build [-registry path] [-images dir] [language key...]
+//	judgectl pin [-registry path] [language key...]
+//
+// build builds the judge/<name>:<tag> images the registry refers to from the
+// Dockerfiles in <dir>/<name>, passing <tag> as the TAG build argument, and
+// records the ID of each build as its digest in the registry file. The judge
+// refuses to start on a different build of a pinned tag, so a rebuild, which
+// may pick up newer base images and packages, changes nothing until the
+// updated registry is deployed.
+//
+// pin pulls the current build of each pulled language image and records its
+// digest in the registry file, so image upgrades happen only when someone runs
+// it and commits the result. Locally built judge/* images are pinned by build
+// instead and skipped.
+//
+// Without keys, every language is built or pinned.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"judging-service/internal/models"
+	"judging-service/internal/registry"
+	"judging-service/internal/sandbox"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	switch os.Args[1] {
+	case "build":
+		buildImages(os.Args[2:])
+	case "pin":
+		pin(os.Args[2:])
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: judgectl build [-registry path] [-images dir] [language key...]")
+	fmt.Fprintln(os.Stderr, "       judgectl pin [-registry path] [language key...]")
+	os.Exit(2)
+}
+
+func pin(args []string) {
+	flags := flag.NewFlagSet("pin", flag.ExitOnError)
+	path := flags.String("registry", "internal/registry/languages.json", "language registry file to update")
+	flags.Parse(args)
+
+	data, err := os.ReadFile(*path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	_, languages := selectLanguages(data, *path, flags.Args())
+	docker := connect()
+	defer docker.Close()
+
+	ctx := context.Background()
+	digests := make(map[string]string)
+	seen := make(map[string]bool)
+	for _, spec := range languages {
+		for _, pinned := range spec.PinnedImages() {
+			image := string(pinned.Name)
+			if seen[image] {
+				continue
+			}
+			seen[image] = true
+			if pinned.Name.IsLocal() {
+				log.Printf("%s: built locally, pinned by judgectl build", image)
+				continue
+			}
+			// Pull the tag itself, not the pinned build, to pick up its latest digest.
+			if err := docker.PullImage(ctx, image, nil); err != nil {
+				log.Fatal(err)
+			}
+			digest, err := docker.ImageDigest(ctx, image)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if digest != pinned.Digest {
+				log.Printf("%s: %s -> %s", image, orNone(pinned.Digest), digest)
+			}
+			digests[image] = digest
+		}
+	}
+
+	pinned, err := registry.PinDigests(data, digests)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*path, pinned, 0644); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Pinned %d images in %s", len(digests), *path)
+}
+
+func buildImages(args []string) {
+	flags := flag.NewFlagSet("build", flag.ExitOnError)
+	path := flags.String("registry", "internal/registry/languages.json", "language registry naming the images, updated with their builds")
+	imagesDir := flags.String("images", "images", "directory holding one Dockerfile directory per image")
+	flags.Parse(args)
+
+	data, err := os.ReadFile(*path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	all, languages := selectLanguages(data, *path, flags.Args())
+	docker := connect()
+	defer docker.Close()
+
+	ctx := context.Background()
+	ids := make(map[string]string)
+	for _, spec := range languages {
+		for _, image := range spec.Images() {
+			if _, ok := ids[string(image)]; !image.IsLocal() || ok {
+				continue
+			}
+			buildImage(ctx, docker, *imagesDir, image, precompiledHeaderFlags(all, image))
+			// Local builds have no registry digest, so this is the image ID.
+			id, err := docker.ImageDigest(ctx, string(image))
+			if err != nil {
+				log.Fatal(err)
+			}
+			log.Printf("%s: built %s", image, id)
+			ids[string(image)] = id
+		}
+	}
+
+	pinned, err := registry.PinDigests(data, ids)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*path, pinned, 0644); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Built %d images and pinned them in %s", len(ids), *path)
+}
+
+func buildImage(ctx context.Context, docker *sandbox.DockerRuntime, imagesDir string, image models.LanguageDockerImageName, pchFlags string) {
+	name, tag, _ := strings.Cut(strings.TrimPrefix(string(image), models.LocalImagePrefix), ":")
+	buildArgs := map[string]string{}
+	if tag != "" {
+		buildArgs["TAG"] = tag
+	}
+	if pchFlags != "" {
+		buildArgs["PCH_FLAGS"] = pchFlags
+	}
+
+	log.Printf("Building %s from %s", image, filepath.Join(imagesDir, name))
+	err := docker.BuildImage(ctx, filepath.Join(imagesDir, name), string(image), buildArgs, func(message string) {
+		log.Printf("%s: %s", image, message)
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// precompiledHeaderFlags lists, separated by ";", the default flags of every
+// language on image that asks for a precompiled header.
+func precompiledHeaderFlags(languages []models.LanguageSpec, image models.LanguageDockerImageName) string {
+	var flagSets []string
+	for _, spec := range languages {
+		if spec.Image != image || !spec.PrecompiledHeader {
+			continue
+		}
+		flags := strings.Join(spec.DefaultFlags, " ")
+		if !slices.Contains(flagSets, flags) {
+			flagSets = append(flagSets, flags)
+		}
+	}
+	return strings.Join(flagSets, ";")
+}
+
+// selectLanguages parses the registry and returns all of its languages and
+// those named by keys, or all of them again when no keys are given.
+func selectLanguages(data []byte, path string, keys []string) (all, selected []models.LanguageSpec) {
+	languages, err := registry.Parse(data)
+	if err != nil {
+		log.Fatalf("invalid language registry %s: %v", path, err)
+	}
+	all = languages.All()
+	if len(keys) == 0 {
+		return all, all
+	}
+	for _, spec := range all {
+		if slices.Contains(keys, string(spec.Key)) {
+			selected = append(selected, spec)
+		}
+	}
+	return all, selected
+}
+
+func connect() *sandbox.DockerRuntime {
+	settings, err := sandbox.DockerSettingsFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	docker, err := sandbox.NewDockerRuntime(settings)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return docker
+}
+
+func orNone(digest string) string {
+	if digest == "" {
+		return "(unpinned)"
+	}
+	return digest
+}
@@ -0,0 +1,96 @@
+package containers
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"judging-service/internal/cache"
+	"judging-service/internal/models"
+	"judging-service/internal/sandbox"
+)
+
+// imageDigestTTL is how long the digest of an unpinned image is remembered, so
+// a rebuilt image is noticed without inspecting it for every compile.
+const imageDigestTTL = time.Minute
+
+type imageDigest struct {
+	digest    string
+	checkedAt time.Time
+}
+
+// ArtifactCacheKey identifies the artifacts of compiling the source files with
+// the given compile command line: the sources, the language variant, the
+// command with its flags, the compile environment, the artifacts kept and the
+// digest of the image the compiler comes from. Without an artifact cache, for
+// languages without artifacts, or when the image can be identified neither by
+// its pin nor through the runtime, nothing can be cached and ok is false.
+func (m *ContainersPoolManger) ArtifactCacheKey(ctx context.Context, spec models.LanguageSpec, options models.SubmissionOptions, compileCommand []string, sources map[string]string) (key string, ok bool) {
+	if m.ArtifactCache == nil || len(spec.Artifacts) == 0 {
+		return "", false
+	}
+	digest, ok := m.imageDigest(ctx, spec.SandboxImage(options))
+	if !ok {
+		return "", false
+	}
+	env := spec.Env
+	if options.Diagnostics && spec.Diagnostics != nil {
+		env = append(slices.Clone(env), spec.Diagnostics.Env...)
+	}
+	parts := []string{
+		string(spec.Key),
+		strings.Join(compileCommand, "\x00"),
+		strings.Join(env, "\x00"),
+		strings.Join(spec.Artifacts, "\x00"),
+		digest,
+	}
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		parts = append(parts, name, sources[name])
+	}
+	return cache.Key(parts...), true
+}
+
+// imageDigest identifies the build of an image. A pinned image is its digest,
+// which PrepareImages verified at startup. An unpinned one is looked up
+// through the runtime and remembered for imageDigestTTL.
+func (m *ContainersPoolManger) imageDigest(ctx context.Context, image models.PinnedImage) (string, bool) {
+	if image.Digest != "" {
+		return image.Digest, true
+	}
+	ref := image.Ref()
+	m.digestsMu.Lock()
+	known, ok := m.digests[ref]
+	m.digestsMu.Unlock()
+	if ok && time.Since(known.checkedAt) < imageDigestTTL {
+		return known.digest, true
+	}
+
+	m.mu.Lock()
+	rt, err := m.sandboxRuntime()
+	m.mu.Unlock()
+	if err != nil {
+		return "", false
+	}
+	inspector, isInspector := rt.(sandbox.ImageInspector)
+	if !isInspector {
+		return "", false
+	}
+	digest, err := inspector.ImageDigest(ctx, ref)
+	if err != nil {
+		return "", false
+	}
+	m.digestsMu.Lock()
+	if m.digests == nil {
+		m.digests = make(map[string]imageDigest)
+	}
+	m.digests[ref] = imageDigest{digest: digest, checkedAt: time.Now()}
+	m.digestsMu.Unlock()
+	return digest, true
+}
@@ -5,70 +5,207 @@ import (
 	"fmt"
 	"log"
 	"sort"
+	"strconv"
 	"sync"
 	"time"
 
-	"github.com/docker/docker/api/types/container"
-	"github.com/docker/docker/client"
+	"judging-service/internal/cache"
 	"judging-service/internal/models"
+	"judging-service/internal/registry"
+	"judging-service/internal/sandbox"
 	"judging-service/internal/service"
 )
 
 type ContainersPoolManger struct {
 	Limit          int
+	Languages      *registry.Registry
 	FreeContainers []*models.Container
 	NextID         int
 	mu             sync.Mutex
+	cores          *coreSet
+	runtime        sandbox.Runtime
+	removals       sync.WaitGroup
+	probesMu       sync.Mutex
+	probes         map[int]LanguageProbe
+	digestsMu      sync.Mutex
+	digests        map[string]imageDigest
+	// ArtifactCache, when set, keeps the compiled programs of languages with
+	// artifacts, so identical submissions are not compiled again.
+	ArtifactCache *cache.Cache
 }
 
+// NewContainersPoolManger creates a pool backed by Docker, connecting to the
+// daemon described by the JUDGE_DOCKER_* environment the first time a container is needed.
 func NewContainersPoolManger(limit int) *ContainersPoolManger {
 	return &ContainersPoolManger{
-		Limit:  limit,
-		NextID: 1,
+		Limit:     limit,
+		Languages: registry.Default(),
+		NextID:    1,
 	}
 }
 
+// NewContainersPoolMangerWithRuntime creates a pool that hosts every container in rt.
+// The pool takes ownership of the runtime and closes it in Close.
+func NewContainersPoolMangerWithRuntime(limit int, rt sandbox.Runtime) *ContainersPoolManger {
+	m := NewContainersPoolManger(limit)
+	m.runtime = rt
+	return m
+}
+
+// sandboxRuntime returns the shared runtime, creating the Docker one on first use. Callers hold mu.
+func (m *ContainersPoolManger) sandboxRuntime() (sandbox.Runtime, error) {
+	if m.runtime != nil {
+		return m.runtime, nil
+	}
+	settings, err := sandbox.DockerSettingsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	rt, err := sandbox.NewDockerRuntime(settings)
+	if err != nil {
+		return nil, err
+	}
+	m.runtime = rt
+	return rt, nil
+}
+
+// Close removes every pooled container and closes the runtime.
+func (m *ContainersPoolManger) Close() error {
+	m.mu.Lock()
+	pooled := m.FreeContainers
+	m.FreeContainers = nil
+	for _, c := range pooled {
+		m.releaseCore(c)
+	}
+	m.mu.Unlock()
+
+	for _, c := range pooled {
+		m.removals.Add(1)
+		m.removeContainer(c)
+	}
+	m.removals.Wait()
+
+	if m.runtime == nil {
+		return nil
+	}
+	return m.runtime.Close()
+}
+
+// EnableCorePinning pins every pool container to its own core taken from cores.
+// The pool never holds more containers than there are cores to pin them to.
+func (m *ContainersPoolManger) EnableCorePinning(cores []int) error {
+	set, err := newCoreSet(cores)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.FreeContainers) > 0 {
+		return fmt.Errorf("core pinning must be enabled before containers are created")
+	}
+	m.cores = set
+	return nil
+}
+
+// capacity is the number of containers the pool may hold at once.
+func (m *ContainersPoolManger) capacity() int {
+	if m.cores != nil && m.cores.size() < m.Limit {
+		return m.cores.size()
+	}
+	return m.Limit
+}
+
+// sandboxRequest is the image and resources of a container to create.
+type sandboxRequest struct {
+	image    string
+	memoryMB int
+	nanoCPUs int64
+}
+
 // GetContainerWithLimits is the new primary method for acquiring a container.
-// It handles language selection and retries, calling the internal get-or-create logic.
-func (m *ContainersPoolManger) GetContainerWithLimits(language int, limit models.ResourceLimit) (*models.Container, models.LangContainer, models.Language, error) {
+// It looks the language up in the registry and retries, calling the internal get-or-create logic.
+// options carry the submission's compile flags and diagnostic mode.
+// The container starts with enough memory to compile; RestrictToRunLimits
+// brings it down to the problem limit before the program runs.
+func (m *ContainersPoolManger) GetContainerWithLimits(language int, limit models.ResourceLimit, options models.SubmissionOptions) (*models.Container, models.LangContainer, models.LanguageSpec, error) {
+	return m.acquire(language, limit, options, func(spec models.LanguageSpec) sandboxRequest {
+		memoryMB := limit.MemoryLimitInMB + spec.MemoryOverheadMB
+		if len(spec.CompileCommand) > 0 {
+			memoryMB = max(memoryMB, spec.CompileMemory())
+		}
+		return sandboxRequest{
+			image:    spec.SandboxImageRef(options),
+			memoryMB: memoryMB,
+			nanoCPUs: int64(limit.CPU) * 1e9,
+		}
+	})
+}
+
+// RestrictToRunLimits lowers a container from GetContainerWithLimits to the
+// problem memory limit once compilation is done.
+func (m *ContainersPoolManger) RestrictToRunLimits(doc *models.Container, spec models.LanguageSpec, limit models.ResourceLimit) error {
+	runMemoryMB := limit.MemoryLimitInMB + spec.MemoryOverheadMB
+	if len(spec.CompileCommand) == 0 || spec.CompileMemory() <= runMemoryMB {
+		return nil
+	}
+	return doc.Runtime.SetMemoryLimit(doc.Ctx, doc.SandboxID, runMemoryMB)
+}
+
+// GetCompileContainer acquires a container of the language's compile image
+// with its compile memory, for languages with a separate run sandbox.
+func (m *ContainersPoolManger) GetCompileContainer(language int, limit models.ResourceLimit, options models.SubmissionOptions) (*models.Container, models.LangContainer, models.LanguageSpec, error) {
+	return m.acquire(language, limit, options, func(spec models.LanguageSpec) sandboxRequest {
+		return sandboxRequest{
+			image:    spec.ImageRef(),
+			memoryMB: spec.CompileMemory(),
+			nanoCPUs: int64(limit.CPU) * 1e9,
+		}
+	})
+}
+
+// GetRunContainer acquires a container of the language's minimal run image,
+// held to the problem limits. The options decide how test cases are fed to the program.
+func (m *ContainersPoolManger) GetRunContainer(language int, limit models.ResourceLimit, options models.SubmissionOptions) (*models.Container, models.LangContainer, models.LanguageSpec, error) {
+	return m.acquire(language, limit, options, func(spec models.LanguageSpec) sandboxRequest {
+		return sandboxRequest{
+			image:    spec.RunImageRef(),
+			memoryMB: limit.MemoryLimitInMB + spec.MemoryOverheadMB,
+			nanoCPUs: int64(limit.CPU) * 1e9,
+		}
+	})
+}
+
+func (m *ContainersPoolManger) acquire(language int, limit models.ResourceLimit, options models.SubmissionOptions, sandboxFor func(models.LanguageSpec) sandboxRequest) (*models.Container, models.LangContainer, models.LanguageSpec, error) {
 	maxAttempts := 50
 	sleepDuration := 1000 * time.Millisecond
 
-	var exec models.LangContainer
-	var lang models.Language
-	switch language {
-	case 1:
-		lang = models.Cpp
-		exec = service.CppRunLangInterFace{}
-	case 0:
-		lang = models.Python
-		exec = service.PythonRunLangInterface{}
-
-	// Add other languages here
-	default:
-		return nil, nil, "", fmt.Errorf("invalid language: %q", language)
+	spec, ok := m.Languages.Lookup(language)
+	if !ok {
+		return nil, nil, models.LanguageSpec{}, fmt.Errorf("invalid language: %d", language)
 	}
+	exec := service.RegistryRunLangInterface{Spec: spec, Limit: limit, Options: options}
+	request := sandboxFor(spec)
 
 	var lastErr error
 	for attempt := 0; attempt < maxAttempts; attempt++ {
-		doc, err := m.getOrCreateContainer(lang, limit)
+		doc, err := m.getOrCreateContainer(spec, request)
 		if err == nil {
-			return doc, exec, lang, nil
+			return doc, exec, spec, nil
 		}
 		lastErr = err
 		time.Sleep(sleepDuration)
 	}
-	return nil, nil, "", fmt.Errorf("after %d attempts, last error: %w", maxAttempts, lastErr)
+	return nil, nil, models.LanguageSpec{}, fmt.Errorf("after %d attempts, last error: %w", maxAttempts, lastErr)
 }
 
 // getOrCreateContainer implements the logic to always create a new container,
 // evicting an old one if the pool is full.
-func (manger *ContainersPoolManger) getOrCreateContainer(language models.Language, limit models.ResourceLimit) (*models.Container, error) {
+func (manger *ContainersPoolManger) getOrCreateContainer(spec models.LanguageSpec, request sandboxRequest) (*models.Container, error) {
 	manger.mu.Lock()
 	defer manger.mu.Unlock()
 
 	// If the pool is full, find and evict an old, empty container.
-	if len(manger.FreeContainers) >= manger.Limit {
+	if len(manger.FreeContainers) >= manger.capacity() {
 		log.Println("Pool is full. Searching for a container to evict.")
 		// Sort to find the oldest container reliably.
 		sort.Slice(manger.FreeContainers, func(i, j int) bool {
@@ -91,19 +228,22 @@ func (manger *ContainersPoolManger) getOrCreateContainer(language models.Languag
 		// Efficiently remove the container from the slice.
 		manger.FreeContainers[evictedIndex] = manger.FreeContainers[len(manger.FreeContainers)-1]
 		manger.FreeContainers = manger.FreeContainers[:len(manger.FreeContainers)-1]
+		// The evicted container is idle, so its core can be handed out right away.
+		manger.releaseCore(oldContainer)
 
 		// Asynchronously clean up the old container.
+		manger.removals.Add(1)
 		go manger.removeContainer(oldContainer)
 		log.Printf("Evicted container ID %d. Creating new container.", oldContainer.ID)
 	}
 
 	// Create and add the new container.
-	return manger.createAndAddContainer(language, limit)
+	return manger.createAndAddContainer(spec, request)
 }
 
 // createAndAddContainer is a helper to create and append a new container.
-func (manger *ContainersPoolManger) createAndAddContainer(lang models.Language, limit models.ResourceLimit) (*models.Container, error) {
-	newContainer, err := manger.newDockerContainer(lang, limit)
+func (manger *ContainersPoolManger) createAndAddContainer(spec models.LanguageSpec, request sandboxRequest) (*models.Container, error) {
+	newContainer, err := manger.newContainer(spec, request)
 	if err != nil {
 		return nil, err
 	}
@@ -111,13 +251,19 @@ func (manger *ContainersPoolManger) createAndAddContainer(lang models.Language,
 	return newContainer, nil
 }
 
-// removeContainer stops and removes a Docker container in the background.
+func (manger *ContainersPoolManger) releaseCore(c *models.Container) {
+	if manger.cores != nil && c.CPUCore >= 0 {
+		manger.cores.release(c.CPUCore)
+	}
+}
+
+// removeContainer stops and removes a container in the background.
+// Callers register the removal with manger.removals before calling it.
 func (manger *ContainersPoolManger) removeContainer(c *models.Container) {
-	log.Printf("Scheduling asynchronous removal of container ID: %s", c.ContainerResp.ID)
-	ctx := context.Background()
-	_ = c.Cli.ContainerStop(ctx, c.ContainerResp.ID, container.StopOptions{})
-	_ = c.Cli.ContainerRemove(ctx, c.ContainerResp.ID, container.RemoveOptions{Force: true})
-	log.Printf("Asynchronous removal of container ID %s completed.", c.ContainerResp.ID)
+	defer manger.removals.Done()
+	log.Printf("Scheduling asynchronous removal of container ID: %s", c.SandboxID)
+	_ = c.Runtime.Remove(context.Background(), c.SandboxID)
+	log.Printf("Asynchronous removal of container ID %s completed.", c.SandboxID)
 }
 
 func (manger *ContainersPoolManger) FreeContainer(container *models.Container) {
@@ -133,59 +279,46 @@ func (manger *ContainersPoolManger) FreeContainer(container *models.Container) {
 	}
 }
 
-// newDockerContainer now accepts resource limits.
-func (manger *ContainersPoolManger) newDockerContainer(lang models.Language, limit models.ResourceLimit) (*models.Container, error) {
-	docker := &models.Container{
+// newContainer creates a container for the language as described by request.
+func (manger *ContainersPoolManger) newContainer(spec models.LanguageSpec, request sandboxRequest) (*models.Container, error) {
+	doc := &models.Container{
 		Ctx:          context.Background(),
 		ID:           manger.NextID,
-		Language:     lang,
+		Language:     spec.Key,
 		IsEmpty:      false,
 		IsInit:       true,
 		LastModified: time.Now(),
+		CPUCore:      -1,
 	}
 	manger.NextID++
 
-	var dockerImage models.LanguageDockerImageName
-	switch lang {
-	case models.Cpp:
-		dockerImage = models.CppImage
-	case models.Python:
-		dockerImage = models.PythonImage
-	default:
-		return nil, fmt.Errorf("unsupported language for docker image: %s", lang)
-	}
-
-	cli, err := client.NewClientWithOpts(client.FromEnv)
+	rt, err := manger.sandboxRuntime()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create Docker client: %v", err)
-	}
-	docker.Cli = cli
-	docker.Cli.NegotiateAPIVersion(docker.Ctx)
-
-	containerConfig := &container.Config{
-		Image:      string(dockerImage),
-		Tty:        false,
-		Cmd:        []string{"sleep", "600"},
-		WorkingDir: "/workspace",
+		return nil, err
 	}
+	doc.Runtime = rt
 
-	// Apply resource limits to the HostConfig.
-	hostConfig := &container.HostConfig{
-		Resources: container.Resources{
-			Memory:   int64(limit.MemoryLimitInMB) * 1024 * 1024,
-			CPUCount: int64(limit.CPU),
-		},
+	sandboxSpec := sandbox.Spec{
+		Image:           request.image,
+		WorkingDir:      "/workspace",
+		MemoryLimitInMB: request.memoryMB,
+		NanoCPUs:        request.nanoCPUs,
 	}
 
-	resp, err := docker.Cli.ContainerCreate(docker.Ctx, containerConfig, hostConfig, nil, nil, "")
-	if err != nil {
-		return nil, fmt.Errorf("failed to create container: %v", err)
+	if manger.cores != nil {
+		core, ok := manger.cores.acquire()
+		if !ok {
+			return nil, fmt.Errorf("no free cpu core to pin the container to")
+		}
+		doc.CPUCore = core
+		sandboxSpec.CpusetCpus = strconv.Itoa(core)
 	}
-	docker.ContainerResp = resp
 
-	err = docker.Cli.ContainerStart(docker.Ctx, docker.ContainerResp.ID, container.StartOptions{})
+	id, err := rt.Create(doc.Ctx, sandboxSpec)
 	if err != nil {
-		return nil, fmt.Errorf("failed to start container: %v", err)
+		manger.releaseCore(doc)
+		return nil, err
 	}
-	return docker, nil
+	doc.SandboxID = id
+	return doc, nil
 }
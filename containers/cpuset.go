@@ -0,0 +1,124 @@
+package containers
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// onlineCPUsFile lists the host's online cores, whatever the affinity of the
+// judge process itself, which runtime.NumCPU reports.
+const onlineCPUsFile = "/sys/devices/system/cpu/online"
+
+// coreSet hands out dedicated CPU cores to pool containers so that no two
+// containers are ever pinned to the same core. It is guarded by the pool mutex.
+type coreSet struct {
+	cores []int
+	inUse map[int]bool
+}
+
+func newCoreSet(cores []int) (*coreSet, error) {
+	if len(cores) == 0 {
+		return nil, fmt.Errorf("core pinning needs at least one core")
+	}
+	online := onlineCPUs()
+	seen := make(map[int]bool, len(cores))
+	for _, core := range cores {
+		if !slices.Contains(online, core) {
+			return nil, fmt.Errorf("core %d is not online on this host (online cores: %s)", core, formatCPUList(online))
+		}
+		if seen[core] {
+			return nil, fmt.Errorf("core %d listed more than once", core)
+		}
+		seen[core] = true
+	}
+	sorted := append([]int(nil), cores...)
+	sort.Ints(sorted)
+	return &coreSet{cores: sorted, inUse: make(map[int]bool, len(sorted))}, nil
+}
+
+// acquire reserves the lowest free core, or reports false if every core is taken.
+func (s *coreSet) acquire() (int, bool) {
+	for _, core := range s.cores {
+		if !s.inUse[core] {
+			s.inUse[core] = true
+			return core, true
+		}
+	}
+	return -1, false
+}
+
+func (s *coreSet) release(core int) {
+	delete(s.inUse, core)
+}
+
+func (s *coreSet) size() int {
+	return len(s.cores)
+}
+
+// onlineCPUs lists the host's online cores, falling back to as many cores as
+// the process may use where the kernel does not list them.
+func onlineCPUs() []int {
+	if data, err := os.ReadFile(onlineCPUsFile); err == nil {
+		if cores, err := ParseCPUList(strings.TrimSpace(string(data))); err == nil && len(cores) > 0 {
+			return cores
+		}
+	}
+	cores := make([]int, runtime.NumCPU())
+	for i := range cores {
+		cores[i] = i
+	}
+	return cores
+}
+
+// formatCPUList is the inverse of ParseCPUList for sorted cores.
+func formatCPUList(cores []int) string {
+	var parts []string
+	for i := 0; i < len(cores); {
+		j := i
+		for j+1 < len(cores) && cores[j+1] == cores[j]+1 {
+			j++
+		}
+		if i == j {
+			parts = append(parts, strconv.Itoa(cores[i]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", cores[i], cores[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ",")
+}
+
+// ParseCPUList parses a Linux cpuset list such as "0-3,6" into core numbers.
+func ParseCPUList(list string) ([]int, error) {
+	var cores []int
+	for _, part := range strings.Split(list, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		from, to, isRange := strings.Cut(part, "-")
+		start, err := strconv.Atoi(from)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cpu list entry %q: %w", part, err)
+		}
+		end := start
+		if isRange {
+			end, err = strconv.Atoi(to)
+			if err != nil {
+				return nil, fmt.Errorf("invalid cpu list entry %q: %w", part, err)
+			}
+		}
+		if end < start {
+			return nil, fmt.Errorf("invalid cpu range %q", part)
+		}
+		for core := start; core <= end; core++ {
+			cores = append(cores, core)
+		}
+	}
+	return cores, nil
+}
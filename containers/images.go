@@ -0,0 +1,91 @@
+package containers
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"judging-service/internal/models"
+	"judging-service/internal/sandbox"
+)
+
+// PrepareImages pulls every image the registered languages use that is not
+// present yet and checks that each pinned image matches its digest, so the
+// first submission neither waits for a pull nor runs on an image other than
+// the configured one. Images already present, including the locally built
+// judge/* images, are not pulled again; a local image is pinned to the image
+// ID judgectl build recorded for it. A pulled image without a digest is
+// refused, as its tag could move under a running contest. Runtimes that do
+// not pull or identify images skip the respective step.
+func (m *ContainersPoolManger) PrepareImages(ctx context.Context) error {
+	m.mu.Lock()
+	rt, err := m.sandboxRuntime()
+	m.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	puller, _ := rt.(sandbox.ImagePuller)
+	inspector, _ := rt.(sandbox.ImageInspector)
+
+	prepared := make(map[models.PinnedImage]bool)
+	for _, spec := range m.Languages.All() {
+		for _, pinned := range spec.PinnedImages() {
+			image := languageImage{ref: pinned.Ref(), name: pinned.Name, pinned: pinned.Digest != ""}
+			if prepared[pinned] {
+				continue
+			}
+			prepared[pinned] = true
+			if !image.pinned && !image.name.IsLocal() && inspector != nil {
+				return fmt.Errorf("image for %q: %s is not pinned by digest; pin it with judgectl pin", spec.Key, image.ref)
+			} else if !image.pinned {
+				log.Printf("Image %s for %q is not pinned by digest", image.ref, spec.Key)
+			}
+			digest, err := prepareImage(ctx, puller, inspector, image)
+			if err != nil {
+				return fmt.Errorf("image for %q: %w", spec.Key, err)
+			}
+			if inspector != nil && image.name.IsLocal() && image.pinned && digest != pinned.Digest {
+				return fmt.Errorf("image for %q: %s is build %s, but the registry pins %s; rebuild it with judgectl build", spec.Key, image.ref, digest, pinned.Digest)
+			}
+		}
+	}
+	return nil
+}
+
+// languageImage is one image a language needs, as the reference sandboxes are created from.
+type languageImage struct {
+	ref    string
+	name   models.LanguageDockerImageName
+	pinned bool
+}
+
+// prepareImage pulls an image unless it is already present, then verifies it
+// and returns its digest. A nil puller or inspector skips that step.
+func prepareImage(ctx context.Context, puller sandbox.ImagePuller, inspector sandbox.ImageInspector, image languageImage) (string, error) {
+	if inspector != nil {
+		if digest, err := inspector.ImageDigest(ctx, image.ref); err == nil {
+			log.Printf("Image %s is present (%s)", image.ref, digest)
+			return digest, nil
+		}
+	}
+	if puller != nil {
+		log.Printf("Pulling image %s", image.ref)
+		err := puller.PullImage(ctx, image.ref, func(message string) {
+			log.Printf("Pulling image %s: %s", image.ref, message)
+		})
+		if err != nil && image.name.IsLocal() {
+			return "", fmt.Errorf("image %s is missing; build it with judgectl build: %w", image.ref, err)
+		} else if err != nil {
+			return "", err
+		}
+	}
+	if inspector == nil {
+		return "", nil
+	}
+	digest, err := inspector.ImageDigest(ctx, image.ref)
+	if err != nil {
+		return "", fmt.Errorf("failed verification: %w", err)
+	}
+	log.Printf("Image %s is ready (%s)", image.ref, digest)
+	return digest, nil
+}
@@ -0,0 +1,104 @@
+package containers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"judging-service/internal/models"
+	"judging-service/internal/sandbox"
+)
+
+const probeTimeout = 30 * time.Second
+
+// LanguageProbe is what the sandbox reports about a language's image.
+type LanguageProbe struct {
+	// Version is the first line the language's version command printed.
+	Version string
+	// ImageDigest is empty when the runtime cannot identify images.
+	ImageDigest string
+}
+
+// ProbeLanguages runs every registered language's version command in a
+// short-lived sandbox of its image and remembers what it reports. Probing can
+// pull images, so it is meant to run once in the background at startup.
+func (m *ContainersPoolManger) ProbeLanguages(ctx context.Context) {
+	for _, spec := range m.Languages.All() {
+		probe, err := m.probeLanguage(ctx, spec)
+		if err != nil {
+			log.Printf("Failed to probe language %q: %v", spec.Key, err)
+			continue
+		}
+		m.probesMu.Lock()
+		if m.probes == nil {
+			m.probes = make(map[int]LanguageProbe)
+		}
+		m.probes[spec.ID] = probe
+		m.probesMu.Unlock()
+	}
+}
+
+// LanguageProbe returns what ProbeLanguages found for a language, if it has been probed.
+func (m *ContainersPoolManger) LanguageProbe(language int) (LanguageProbe, bool) {
+	m.probesMu.Lock()
+	defer m.probesMu.Unlock()
+	probe, ok := m.probes[language]
+	return probe, ok
+}
+
+func (m *ContainersPoolManger) probeLanguage(ctx context.Context, spec models.LanguageSpec) (LanguageProbe, error) {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	m.mu.Lock()
+	rt, err := m.sandboxRuntime()
+	m.mu.Unlock()
+	if err != nil {
+		return LanguageProbe{}, err
+	}
+
+	var probe LanguageProbe
+	if inspector, ok := rt.(sandbox.ImageInspector); ok {
+		if probe.ImageDigest, err = inspector.ImageDigest(ctx, spec.ImageRef()); err != nil {
+			return LanguageProbe{}, err
+		}
+	}
+	if len(spec.VersionCommand) == 0 {
+		return probe, nil
+	}
+
+	id, err := rt.Create(ctx, sandbox.Spec{Image: spec.ImageRef(), WorkingDir: "/workspace", MemoryLimitInMB: 256, NanoCPUs: 1e9})
+	if err != nil {
+		return LanguageProbe{}, err
+	}
+	defer rt.Remove(context.Background(), id)
+
+	result, err := rt.Exec(ctx, id, sandbox.ExecRequest{Cmd: spec.VersionCommand, Env: spec.Env, WorkingDir: "/workspace"})
+	if err != nil {
+		return LanguageProbe{}, err
+	}
+	if result.ExitCode != 0 {
+		return LanguageProbe{}, fmt.Errorf("%s exited with code %d", strings.Join(spec.VersionCommand, " "), result.ExitCode)
+	}
+	// Some tools, like java -version, print their version on stderr.
+	probe.Version = firstLine(result.Stdout)
+	if probe.Version == "" {
+		probe.Version = firstLine(result.Stderr)
+	}
+	if probe.Version == "" {
+		return LanguageProbe{}, errors.New("version command printed nothing")
+	}
+	return probe, nil
+}
+
+func firstLine(s string) string {
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
@@ -0,0 +1,171 @@
+// Package cache keeps compiled artifacts on local disk, addressed by a hash of
+// everything that determines them, and evicts the least recently used entries
+// once the cache grows past its size bound.
+package cache
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Cache is a size-bounded, content-addressed store of artifact sets. It is
+// safe for concurrent use; entries survive restarts.
+type Cache struct {
+	dir      string
+	maxBytes int64
+
+	mu      sync.Mutex
+	size    int64
+	recency *list.List // front is the most recently used; values are *entry
+	entries map[string]*list.Element
+}
+
+type entry struct {
+	key  string
+	size int64
+}
+
+// Key hashes the parts that identify a compilation into a cache key. Parts
+// are length-prefixed, so no two different lists produce the same input.
+func Key(parts ...string) string {
+	h := sha256.New()
+	for _, part := range parts {
+		h.Write([]byte(strconv.Itoa(len(part)) + ":"))
+		h.Write([]byte(part))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// New opens the cache in dir, creating it if needed, and indexes the entries
+// already there by last use.
+func New(dir string, maxBytes int64) (*Cache, error) {
+	if maxBytes <= 0 {
+		return nil, fmt.Errorf("cache size must be positive")
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	c := &Cache{dir: dir, maxBytes: maxBytes, recency: list.New(), entries: make(map[string]*list.Element)}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache directory: %w", err)
+	}
+	type existing struct {
+		entry
+		used time.Time
+	}
+	var found []existing
+	for _, file := range files {
+		info, err := file.Info()
+		if err != nil || !info.Mode().IsRegular() || filepath.Ext(file.Name()) != "" {
+			// Skips leftovers of interrupted writes, which carry a .tmp suffix.
+			continue
+		}
+		found = append(found, existing{entry{key: file.Name(), size: info.Size()}, info.ModTime()})
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].used.After(found[j].used) })
+	for _, e := range found {
+		c.entries[e.key] = c.recency.PushBack(&entry{key: e.key, size: e.size})
+		c.size += e.size
+	}
+	c.evict()
+	return c, nil
+}
+
+// Get returns the artifacts stored under key and marks them as recently used.
+func (c *Cache) Get(key string) (map[string][]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		c.remove(element)
+		return nil, false
+	}
+	var files map[string][]byte
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&files); err != nil {
+		c.remove(element)
+		return nil, false
+	}
+	c.recency.MoveToFront(element)
+	// The modification time records last use across restarts.
+	now := time.Now()
+	_ = os.Chtimes(c.path(key), now, now)
+	return files, true
+}
+
+// Put stores artifacts under key, evicting least recently used entries as
+// needed. Artifact sets larger than the whole cache are not stored.
+func (c *Cache) Put(key string, files map[string][]byte) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(files); err != nil {
+		return fmt.Errorf("failed to encode artifacts: %w", err)
+	}
+	size := int64(buf.Len())
+	if size > c.maxBytes {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[key]; ok {
+		c.recency.MoveToFront(element)
+		return nil
+	}
+
+	// Write under a temporary name and rename, so readers never see a partial entry.
+	tmp := c.path(key) + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0600); err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := os.Rename(tmp, c.path(key)); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	c.entries[key] = c.recency.PushFront(&entry{key: key, size: size})
+	c.size += size
+	c.evict()
+	return nil
+}
+
+// Size is the total size in bytes of the stored entries.
+func (c *Cache) Size() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size
+}
+
+// evict drops least recently used entries until the cache fits. Callers hold mu.
+func (c *Cache) evict() {
+	for c.size > c.maxBytes {
+		c.remove(c.recency.Back())
+	}
+}
+
+// remove forgets an entry and deletes its file. Callers hold mu.
+func (c *Cache) remove(element *list.Element) {
+	e := element.Value.(*entry)
+	c.recency.Remove(element)
+	delete(c.entries, e.key)
+	c.size -= e.size
+	_ = os.Remove(c.path(e.key))
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.dir, key)
+}
@@ -5,6 +5,8 @@ import "fmt"
 type CompilationError struct {
 	Operation string
 	Limit     int
+	// Output holds the compiler diagnostics, truncated to a reportable size.
+	Output string
 }
 
 func (e *CompilationError) Error() string {
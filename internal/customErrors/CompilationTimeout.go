@@ -0,0 +1,11 @@
+package customErrors
+
+import "fmt"
+
+type CompilationTimeoutError struct {
+	Limit int
+}
+
+func (e *CompilationTimeoutError) Error() string {
+	return fmt.Sprintf("Compilation timed out after : %v s", e.Limit)
+}
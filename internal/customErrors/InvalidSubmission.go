@@ -0,0 +1,12 @@
+package customErrors
+
+import "fmt"
+
+type InvalidSubmissionError struct {
+	// Reason says why the submission was refused, e.g. "the project has 80 files, the limit is 64".
+	Reason string
+}
+
+func (e *InvalidSubmissionError) Error() string {
+	return fmt.Sprintf("Invalid submission: %s", e.Reason)
+}
@@ -0,0 +1,15 @@
+package customErrors
+
+import (
+	"fmt"
+	"strings"
+)
+
+type PolicyViolationError struct {
+	// Violations describe each forbidden use, e.g. "line 2: header <algorithm> is forbidden".
+	Violations []string
+}
+
+func (e *PolicyViolationError) Error() string {
+	return fmt.Sprintf("Policy violation: %s", strings.Join(e.Violations, "; "))
+}
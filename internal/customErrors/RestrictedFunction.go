@@ -0,0 +1,12 @@
+package customErrors
+
+import "fmt"
+
+type RestrictedFunctionError struct {
+	// Function names what the submission was not allowed to use, e.g. "import os".
+	Function string
+}
+
+func (e *RestrictedFunctionError) Error() string {
+	return fmt.Sprintf("Restricted function: %s is not allowed", e.Function)
+}
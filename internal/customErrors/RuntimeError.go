@@ -0,0 +1,14 @@
+package customErrors
+
+import "fmt"
+
+type RuntimeError struct {
+	ExitCode int
+	// Report holds the program's stderr, e.g. a sanitizer report, truncated to
+	// a reportable size. It is only collected in diagnostic mode.
+	Report string
+}
+
+func (e *RuntimeError) Error() string {
+	return fmt.Sprintf("Runtime error: exit code %d", e.ExitCode)
+}
@@ -0,0 +1,16 @@
+package models
+
+// DriverSolutionMarker marks where a driver template takes the submission.
+const DriverSolutionMarker = "{{solution}}"
+
+// Driver is problem-provided harness code for function-signature problems:
+// the submission only implements a function, and the driver reads the input,
+// calls it and prints the result.
+type Driver struct {
+	// Template is the program that is compiled and run, with the submission
+	// injected in place of DriverSolutionMarker.
+	Template string `json:"template"`
+	// Files are written to the workspace next to the program, e.g. headers
+	// the template includes, keyed by their relative path.
+	Files map[string]string `json:"files,omitempty"`
+}
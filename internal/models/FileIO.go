@@ -0,0 +1,12 @@
+package models
+
+// FileIO is the file-based I/O of classic olympiad problems, where the
+// program reads its input from a named file and writes its answer to another
+// instead of using stdin and stdout. Either name may be left empty to keep
+// that side on the standard stream.
+type FileIO struct {
+	// Input, e.g. "input.txt", holds the test input when the program starts.
+	Input string `json:"input,omitempty"`
+	// Output, e.g. "output.txt", is read as the program's output once it exits.
+	Output string `json:"output,omitempty"`
+}
@@ -0,0 +1,20 @@
+package models
+
+// Grader is problem-provided code linked with the submission, as in IOI-style
+// tasks: the grader holds the entry point and calls the functions the
+// submission implements against a header or module the problem provides.
+type Grader struct {
+	// Files are the grader's sources and headers keyed by their relative path.
+	// They are written after the submission and read-only, so the submission
+	// cannot replace them, and removed once compiled unless Entry is set.
+	Files map[string]string `json:"files"`
+	// Sources are the files among Files compiled together with the
+	// submission, e.g. "grader.cpp".
+	Sources []string `json:"sources,omitempty"`
+	// Entry, when set, is run in place of the submission, e.g. "grader.py"
+	// for a grader that imports the submission as a module.
+	Entry string `json:"entry,omitempty"`
+	// SubmissionFile, when set, is the name the submission is written to,
+	// e.g. "task.cpp" or "task.py".
+	SubmissionFile string `json:"submissionFile,omitempty"`
+}
@@ -1,8 +1,3 @@
 package models
 
 type Language string
-
-const (
-	Cpp    Language = "cpp"
-	Python Language = "python"
-)
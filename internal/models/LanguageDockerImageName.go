@@ -1,8 +1,30 @@
 package models
 
+import "strings"
+
 type LanguageDockerImageName string
 
-const (
-	CppImage    LanguageDockerImageName = "gcc:latest"
-	PythonImage LanguageDockerImageName = "python:3.11-alpine"
-)
+// LocalImagePrefix marks the images built from the Dockerfiles under images/,
+// e.g. judge/gcc:14 is built from images/gcc. They exist only locally.
+const LocalImagePrefix = "judge/"
+
+// IsLocal reports whether the image is built locally rather than pulled.
+func (i LanguageDockerImageName) IsLocal() bool {
+	return strings.HasPrefix(string(i), LocalImagePrefix)
+}
+
+// PinnedImage is an image together with the digest pinning it, if any.
+type PinnedImage struct {
+	Name   LanguageDockerImageName
+	Digest string
+}
+
+// Ref is the image reference sandboxes are created from, pinned by digest when
+// one is set. Local images have no registry digest to be referenced by, so
+// their tag is used and the recorded image ID is checked at startup instead.
+func (p PinnedImage) Ref() string {
+	if p.Digest == "" || p.Name.IsLocal() {
+		return string(p.Name)
+	}
+	return string(p.Name) + "@" + p.Digest
+}
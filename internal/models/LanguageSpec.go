@@ -0,0 +1,168 @@
+package models
+
+import "time"
+
+const defaultCompileTimeout = 10 * time.Second
+
+// defaultCompileMemoryMB leaves room for template-heavy C++ whatever the problem limit.
+const defaultCompileMemoryMB = 1024
+
+// LanguageSpec declares how submissions in one language are compiled and run.
+// Commands may use the {source} placeholder for the submitted file name, {class}
+// for that name without its extension and {memoryMB} for the problem memory limit.
+// A compile command argument that is exactly {flags} expands to the compile flags.
+type LanguageSpec struct {
+	ID          int      `json:"id"`
+	Key         Language `json:"key"`
+	DisplayName string   `json:"displayName"`
+	// Family groups the versions and compilers of one language, e.g. every C++
+	// variant has family "cpp". It defaults to the key.
+	Family Language `json:"family,omitempty"`
+	// Version is the language standard or runtime release the variant targets.
+	Version string                  `json:"version,omitempty"`
+	Image   LanguageDockerImageName `json:"image"`
+	// RunImage, when set, runs the compiled program in a separate minimal
+	// sandbox of this image; Image is then only used to compile. Only the
+	// Artifacts are copied from the compile sandbox to the run sandbox.
+	RunImage LanguageDockerImageName `json:"runImage,omitempty"`
+	// Artifacts are the files of the compiled program. A pattern such as
+	// "*.class" names every file it matches anywhere in the workspace. They
+	// are what the artifact cache keeps; languages without them are compiled
+	// for every test case.
+	Artifacts []string `json:"artifacts,omitempty"`
+	// ImageDigest pins Image to one exact build, e.g. "sha256:…", so a
+	// re-tagged image cannot change verdicts. Empty means the tag floats. For
+	// a locally built judge/* image it is the image ID recorded when it was
+	// built, checked at startup.
+	ImageDigest string `json:"imageDigest,omitempty"`
+	// RunImageDigest pins RunImage the same way.
+	RunImageDigest string   `json:"runImageDigest,omitempty"`
+	SourceFile     string   `json:"sourceFile"`
+	CompileCommand []string `json:"compileCommand,omitempty"`
+	// DefaultFlags fill the {flags} placeholder unless a problem overrides them.
+	DefaultFlags []string `json:"defaultFlags,omitempty"`
+	// RunImageFlags always follow {flags} when building for RunImage, whatever
+	// flags a problem sets, e.g. "-static" as the minimal run image has no
+	// shared libraries. Diagnostic builds run where they are compiled and
+	// leave them out.
+	RunImageFlags  []string `json:"runImageFlags,omitempty"`
+	RunCommand     []string `json:"runCommand"`
+	TimeMultiplier float64  `json:"timeMultiplier"`
+	// Env is set for both the compile and the run commands.
+	Env []string `json:"env,omitempty"`
+	// CompileTimeoutSeconds bounds the compile step; zero means the 10 s default.
+	CompileTimeoutSeconds float64 `json:"compileTimeoutSeconds,omitempty"`
+	// CompileMemoryMB is the sandbox memory while compiling, independent of
+	// the problem limit; zero means the 1024 MB default.
+	CompileMemoryMB int `json:"compileMemoryMB,omitempty"`
+	// MemoryOverheadMB is added to the sandbox memory on top of the problem
+	// limit, for runtimes such as the JVM that need memory beyond the heap.
+	MemoryOverheadMB int `json:"memoryOverheadMB,omitempty"`
+	// PrecompiledHeader asks the language's judge/* image to precompile
+	// <bits/stdc++.h> with DefaultFlags; GCC then uses it automatically
+	// whenever a submission is compiled with exactly those flags.
+	PrecompiledHeader bool `json:"precompiledHeader,omitempty"`
+	// VersionCommand prints the compiler or interpreter version, e.g. ["g++", "--version"].
+	VersionCommand []string `json:"versionCommand,omitempty"`
+	// SingleSource marks compilers that take only the entry file of a
+	// project and find its other files themselves, as rustc does with
+	// modules; the other source files are otherwise compiled along with it.
+	SingleSource bool `json:"singleSource,omitempty"`
+	// DetectMainClass names the source file after the submission's public class.
+	DetectMainClass bool `json:"detectMainClass,omitempty"`
+	// Syntax names how the policy checker tokenizes the language's source,
+	// e.g. "c" or "python"; problems can only set policy rules for languages
+	// that have one.
+	Syntax string `json:"syntax,omitempty"`
+	// ModuleRestrictions enforces a problem's allowed and denied modules with
+	// the judge's Python import hook, which the image's interpreter loads
+	// through PYTHONPATH.
+	ModuleRestrictions bool `json:"moduleRestrictions,omitempty"`
+	// Diagnostics is how submissions that ask for a diagnostic run are built
+	// and run; nil means the usual build and run, with stderr still reported.
+	Diagnostics *DiagnosticMode `json:"diagnostics,omitempty"`
+}
+
+// DiagnosticMode instruments a language's build and run to explain runtime
+// errors, e.g. with AddressSanitizer, at the cost of speed.
+type DiagnosticMode struct {
+	// Image, when set, replaces the language's image, for toolchains whose
+	// usual image lacks the sanitizer runtimes (e.g. musl-based ones).
+	Image LanguageDockerImageName `json:"image,omitempty"`
+	// ImageDigest pins Image the same way as the language's own image.
+	ImageDigest string `json:"imageDigest,omitempty"`
+	// Flags fill the {flags} placeholder in place of both the default and the
+	// problem's compile flags, as instrumented builds need their own, e.g.
+	// sanitizers cannot be linked statically.
+	Flags []string `json:"flags,omitempty"`
+	// RunCommand replaces the run command when set.
+	RunCommand []string `json:"runCommand,omitempty"`
+	// Env is added to the language's environment for both commands.
+	Env []string `json:"env,omitempty"`
+}
+
+// ImageRef is the image reference sandboxes are created from, pinned by digest when one is set.
+func (s LanguageSpec) ImageRef() string {
+	return PinnedImage{Name: s.Image, Digest: s.ImageDigest}.Ref()
+}
+
+// RunImageRef is ImageRef for the run sandbox.
+func (s LanguageSpec) RunImageRef() string {
+	return PinnedImage{Name: s.RunImage, Digest: s.RunImageDigest}.Ref()
+}
+
+// SandboxImage is the image a submission with the given options compiles in,
+// and runs in too when it does both in one sandbox.
+func (s LanguageSpec) SandboxImage(options SubmissionOptions) PinnedImage {
+	if options.Diagnostics && s.Diagnostics != nil && s.Diagnostics.Image != "" {
+		return PinnedImage{Name: s.Diagnostics.Image, Digest: s.Diagnostics.ImageDigest}
+	}
+	return PinnedImage{Name: s.Image, Digest: s.ImageDigest}
+}
+
+// SandboxImageRef is the reference of SandboxImage.
+func (s LanguageSpec) SandboxImageRef(options SubmissionOptions) string {
+	return s.SandboxImage(options).Ref()
+}
+
+// PinnedImages lists every image the language uses with its digest: its
+// image, then its run and diagnostic images when it has them.
+func (s LanguageSpec) PinnedImages() []PinnedImage {
+	images := []PinnedImage{{Name: s.Image, Digest: s.ImageDigest}}
+	if s.RunImage != "" {
+		images = append(images, PinnedImage{Name: s.RunImage, Digest: s.RunImageDigest})
+	}
+	if s.Diagnostics != nil && s.Diagnostics.Image != "" {
+		images = append(images, PinnedImage{Name: s.Diagnostics.Image, Digest: s.Diagnostics.ImageDigest})
+	}
+	return images
+}
+
+// Images lists the names of PinnedImages.
+func (s LanguageSpec) Images() []LanguageDockerImageName {
+	var names []LanguageDockerImageName
+	for _, image := range s.PinnedImages() {
+		names = append(names, image.Name)
+	}
+	return names
+}
+
+// SeparateRunSandbox reports whether the program is compiled and run in different sandboxes.
+func (s LanguageSpec) SeparateRunSandbox() bool {
+	return s.RunImage != ""
+}
+
+// CompileMemory is the sandbox memory in MB while compiling.
+func (s LanguageSpec) CompileMemory() int {
+	if s.CompileMemoryMB <= 0 {
+		return defaultCompileMemoryMB
+	}
+	return s.CompileMemoryMB
+}
+
+func (s LanguageSpec) CompileTimeout() time.Duration {
+	if s.CompileTimeoutSeconds <= 0 {
+		return defaultCompileTimeout
+	}
+	return time.Duration(s.CompileTimeoutSeconds * float64(time.Second))
+}
@@ -0,0 +1,14 @@
+package models
+
+// Project is a submission made of several files, such as a multi-file C++
+// program or a Python package, in place of a single source.
+type Project struct {
+	// Files are the submission's files keyed by their relative path.
+	Files map[string]string
+	// Entry is the file the language's commands refer to as {source}, e.g.
+	// "main.cpp" or "app/main.py"; it defaults to the language's source file.
+	Entry string
+	// BuildCommand, when set, replaces the language's compile command and
+	// takes the same placeholders.
+	BuildCommand []string
+}
@@ -0,0 +1,28 @@
+package models
+
+// SubmissionOptions are the per-submission choices on top of the language
+// spec that change how the code is built and run.
+type SubmissionOptions struct {
+	// CompileFlags replace the language's default compile flags when not nil.
+	CompileFlags []string
+	// Diagnostics builds and runs the code in the language's diagnostic mode
+	// and reports what the program printed to stderr when it fails.
+	Diagnostics bool
+	// RuntimeErrorOnNonZeroExit fails a test case whose program exits with a
+	// non-zero code or is killed, whatever it printed. Diagnostic runs always
+	// do; otherwise the output is judged as usual.
+	RuntimeErrorOnNonZeroExit bool
+	// AllowedModules, when not empty, are the only modules the submission may
+	// import, and it may never import DeniedModules. Submodules are covered
+	// by their package. Only languages with ModuleRestrictions enforce them.
+	AllowedModules []string
+	DeniedModules  []string
+	// Driver, when set, is the harness the submission is injected into.
+	Driver *Driver
+	// Grader, when set, is the problem's code compiled and run with the submission.
+	Grader *Grader
+	// Project, when set, holds the submission's files in place of its code.
+	Project *Project
+	// FileIO, when set, passes test input and output through files.
+	FileIO *FileIO
+}
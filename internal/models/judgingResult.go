@@ -6,4 +6,19 @@ type JudgingResult struct {
 	FallingTest  int              `json:"FallingTest"`
 	Verdict      int              `json:"Verdict"`
 	Outputs      []TestCaseOutput `json:"Outputs"`
+	// Reason refines the verdict, e.g. a compilation timeout versus a
+	// compilation error, which share verdict 3.
+	Reason ProblemState `json:"Reason,omitempty"`
+	// ReasonDetail explains the reason where it needs it, e.g. naming the
+	// restricted module a submission imported.
+	ReasonDetail string `json:"ReasonDetail,omitempty"`
+	// CompileCommand is the compile command line the submission was built with.
+	CompileCommand string `json:"CompileCommand,omitempty"`
+	// CompileTimeMs is how long compiling the submission took.
+	CompileTimeMs int64 `json:"CompileTimeMs,omitempty"`
+	// CompilationOutput carries the compiler diagnostics when compilation failed.
+	CompilationOutput string `json:"CompilationOutput,omitempty"`
+	// DiagnosticReport carries the failing program's stderr, e.g. a sanitizer
+	// report, for submissions judged in diagnostic mode.
+	DiagnosticReport string `json:"DiagnosticReport,omitempty"`
 }
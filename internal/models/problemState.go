@@ -1,9 +1,25 @@
 package models
 
-type problemState string
+// ProblemState is the reason behind a judging result's verdict.
+type ProblemState string
 
 const (
-	Passed              problemState = "Passed"
-	TimeLimitExceeded   problemState = "TimeLimitExceeded"
-	MemoryLimitExceeded problemState = "MemoryLimitExceeded"
+	Passed              ProblemState = "Passed"
+	TimeLimitExceeded   ProblemState = "TimeLimitExceeded"
+	MemoryLimitExceeded ProblemState = "MemoryLimitExceeded"
+	CompilationError    ProblemState = "CompilationError"
+	// CompilationTimeout is a compiler exceeding the language's compile time
+	// limit, as opposed to the program exceeding the problem time limit.
+	CompilationTimeout ProblemState = "CompilationTimeout"
+	// RuntimeError is the program exiting with a non-zero code or a signal.
+	RuntimeError ProblemState = "RuntimeError"
+	// RestrictedFunction is the submission using a module or function the
+	// problem forbids.
+	RestrictedFunction ProblemState = "RestrictedFunction"
+	// PolicyViolation is the source using a header or function the problem
+	// forbids, found before compiling.
+	PolicyViolation ProblemState = "PolicyViolation"
+	// InvalidSubmission is a submission that cannot be judged as sent, e.g. a
+	// project over its file limits or an unreadable archive.
+	InvalidSubmission ProblemState = "InvalidSubmission"
 )
@@ -5,5 +5,13 @@ import "context"
 type LangContainer interface {
 	CopyCodeToFile(*Container, string) (string, error)
 	CompileCode(*Container, string, context.Context) (string, error)
-	RunTestCases(*Container, string, string, context.Context) (string, error)
+	// RunTestCases runs the program compiled from the file against a test case.
+	RunTestCases(*Container, string, string, string, context.Context) (string, error)
+	// ReadArtifacts collects the compiled program from a compile container,
+	// and CopyArtifacts installs it in a run container.
+	ReadArtifacts(*Container, string) (map[string][]byte, error)
+	CopyArtifacts(*Container, map[string][]byte) error
+	// InstallArtifacts stands in for CompileCode with the artifacts of an
+	// identical earlier compile and returns the run command.
+	InstallArtifacts(*Container, string, map[string][]byte, context.Context) (string, error)
 }
@@ -0,0 +1,124 @@
+// Package policy checks submissions against a problem's source-level rules,
+// such as a sorting assignment forbidding <algorithm> or sorted(), before
+// anything is compiled. Sources are scanned into tokens, so comments and
+// string literals never trigger a rule and formatting cannot dodge one.
+package policy
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Syntax selects how a language's source is split into tokens.
+type Syntax string
+
+const (
+	// C covers C and C++: // and /* */ comments, quoted and raw string
+	// literals, character literals and #include directives.
+	C Syntax = "c"
+	// Python covers # comments and every kind of string literal; the
+	// expressions inside f-strings are checked like any other code.
+	Python Syntax = "python"
+)
+
+// Supported reports whether submissions in syntax can be checked.
+func Supported(syntax Syntax) bool {
+	return syntax == C || syntax == Python
+}
+
+// Covers reports whether the file of a multi-file submission named fileName
+// holds source in syntax, judging by its extension.
+func (syntax Syntax) Covers(fileName string) bool {
+	switch strings.ToLower(path.Ext(fileName)) {
+	case ".c", ".cc", ".cpp", ".cxx", ".h", ".hh", ".hpp", ".hxx":
+		return syntax == C
+	case ".py":
+		return syntax == Python
+	}
+	return false
+}
+
+// Rules are what a problem forbids submissions in one language to use.
+type Rules struct {
+	// ForbiddenHeaders are headers that may not be included, e.g. "algorithm".
+	// They only apply to C and C++.
+	ForbiddenHeaders []string `json:"forbiddenHeaders,omitempty"`
+	// ForbiddenFunctions are names that may not appear in the code at all,
+	// so aliasing a function does not get around the rule. A qualified name
+	// such as "std::sort" or "math.sqrt" only matches written out in full.
+	ForbiddenFunctions []string `json:"forbiddenFunctions,omitempty"`
+}
+
+// Violation is one use of something the rules forbid.
+type Violation struct {
+	// File is set for multi-file submissions.
+	File string
+	Line int
+	// What describes what was used, e.g. "header <algorithm>".
+	What string
+}
+
+func (v Violation) String() string {
+	if v.File != "" {
+		return fmt.Sprintf("%s line %d: %s is forbidden", v.File, v.Line, v.What)
+	}
+	return fmt.Sprintf("line %d: %s is forbidden", v.Line, v.What)
+}
+
+// Check scans source written in syntax and returns every violation of rules,
+// in source order.
+func Check(syntax Syntax, source string, rules Rules) ([]Violation, error) {
+	var tokens []token
+	switch syntax {
+	case C:
+		tokens = scanC(source)
+	case Python:
+		if len(rules.ForbiddenHeaders) > 0 {
+			return nil, fmt.Errorf("header rules do not apply to Python")
+		}
+		tokens = scanPython(source)
+	default:
+		return nil, fmt.Errorf("policy checks are not supported for syntax %q", syntax)
+	}
+
+	var violations []Violation
+	for i, tok := range tokens {
+		if tok.kind == headerToken {
+			for _, header := range rules.ForbiddenHeaders {
+				if tok.text == header {
+					violations = append(violations, Violation{Line: tok.line, What: "header <" + header + ">"})
+				}
+			}
+			continue
+		}
+		for _, function := range rules.ForbiddenFunctions {
+			if matchesName(tokens[i:], function) {
+				violations = append(violations, Violation{Line: tok.line, What: function})
+			}
+		}
+	}
+	return violations, nil
+}
+
+// matchesName reports whether tokens start with name, which may be qualified
+// with "::" or ".".
+func matchesName(tokens []token, name string) bool {
+	separator := "::"
+	if !strings.Contains(name, separator) {
+		separator = "."
+	}
+	parts := strings.Split(name, separator)
+	if len(tokens) < 2*len(parts)-1 {
+		return false
+	}
+	for i, part := range parts {
+		if i > 0 && (tokens[2*i-1].kind != punctToken || tokens[2*i-1].text != separator) {
+			return false
+		}
+		if tok := tokens[2*i]; tok.kind != identToken || tok.text != part {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,335 @@
+package policy
+
+import (
+	"sort"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	identToken tokenKind = iota
+	punctToken
+	// headerToken is the header name of an #include directive, without its
+	// angle brackets or quotes.
+	headerToken
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	line int
+}
+
+// scanner walks a source, recording the line each token starts on. Comments,
+// literals and numbers are skipped without producing tokens.
+type scanner struct {
+	src    string
+	pos    int
+	tokens []token
+	// newlines and splices hold the offsets in src of its newlines and of
+	// the line splices removed from it, to map offsets back to lines.
+	newlines []int
+	splices  []int
+}
+
+func newScanner(src string, splices []int) *scanner {
+	s := &scanner{src: src, splices: splices}
+	for i := 0; i < len(src); i++ {
+		if src[i] == '\n' {
+			s.newlines = append(s.newlines, i)
+		}
+	}
+	return s
+}
+
+// line is the line of the original source the current offset is on.
+func (s *scanner) line() int {
+	return 1 + sort.SearchInts(s.newlines, s.pos) + sort.SearchInts(s.splices, s.pos+1)
+}
+
+func (s *scanner) peek(offset int) byte {
+	if s.pos+offset < len(s.src) {
+		return s.src[s.pos+offset]
+	}
+	return 0
+}
+
+func (s *scanner) emit(kind tokenKind, text string, line int) {
+	s.tokens = append(s.tokens, token{kind: kind, text: text, line: line})
+}
+
+func (s *scanner) advance(n int) {
+	s.pos = min(s.pos+n, len(s.src))
+}
+
+// skipUntil moves past the next occurrence of end, or to the end of the source.
+func (s *scanner) skipUntil(end string) {
+	if i := strings.Index(s.src[s.pos:], end); i >= 0 {
+		s.advance(i + len(end))
+		return
+	}
+	s.advance(len(s.src) - s.pos)
+}
+
+// skipQuoted moves past a literal delimited by quote on one line, honouring
+// backslash escapes. An unterminated literal ends at the end of the line.
+func (s *scanner) skipQuoted(quote byte) {
+	s.pos++
+	for s.pos < len(s.src) {
+		switch s.src[s.pos] {
+		case '\\':
+			s.advance(2)
+			continue
+		case quote:
+			s.pos++
+			return
+		case '\n':
+			return
+		}
+		s.pos++
+	}
+}
+
+func (s *scanner) readIdent() string {
+	start := s.pos
+	for s.pos < len(s.src) && isIdentByte(s.src[s.pos]) {
+		s.pos++
+	}
+	return s.src[start:s.pos]
+}
+
+// skipNumber moves past a numeric literal, including C++14 digit separators
+// and exponent signs.
+func (s *scanner) skipNumber() {
+	for s.pos < len(s.src) {
+		c := s.src[s.pos]
+		switch {
+		case isIdentByte(c) || c == '.' || c == '\'':
+		case (c == '+' || c == '-') && strings.ContainsRune("eEpP", rune(s.src[s.pos-1])):
+		default:
+			return
+		}
+		s.pos++
+	}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\f' || c == '\v'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// isIdentByte accepts every non-ASCII byte, as both languages allow Unicode identifiers.
+func isIdentByte(c byte) bool {
+	return c == '_' || c >= 0x80 || isDigit(c) || (c|0x20 >= 'a' && c|0x20 <= 'z')
+}
+
+func isIdentStart(c byte) bool {
+	return isIdentByte(c) && !isDigit(c)
+}
+
+// scanC tokenizes C or C++ source.
+func scanC(src string) []token {
+	// Backslash-newline splices lines before anything else in C, so it could
+	// split a name; remove the splices first, remembering where they were.
+	var spliced strings.Builder
+	var splices []int
+	for {
+		i := strings.Index(src, "\\\n")
+		if i < 0 {
+			spliced.WriteString(src)
+			break
+		}
+		spliced.WriteString(src[:i])
+		splices = append(splices, spliced.Len())
+		src = src[i+2:]
+	}
+	s := newScanner(spliced.String(), splices)
+	lineStart := true
+	for s.pos < len(s.src) {
+		c := s.src[s.pos]
+		switch {
+		case c == '\n':
+			s.advance(1)
+			lineStart = true
+			continue
+		case isSpace(c):
+			s.pos++
+			continue
+		case c == '/' && s.peek(1) == '/':
+			s.skipUntil("\n")
+			lineStart = true
+			continue
+		case c == '/' && s.peek(1) == '*':
+			s.advance(2)
+			s.skipUntil("*/")
+			continue
+		case c == '#' && lineStart:
+			s.scanDirective()
+		case c == '"' || c == '\'':
+			s.skipQuoted(c)
+		case isDigit(c) || (c == '.' && isDigit(s.peek(1))):
+			s.skipNumber()
+		case isIdentStart(c):
+			line := s.line()
+			ident := s.readIdent()
+			switch {
+			case s.peek(0) == '"' && strings.HasSuffix(ident, "R") && isStringPrefix(strings.TrimSuffix(ident, "R")):
+				s.skipRawString()
+			case (s.peek(0) == '"' || s.peek(0) == '\'') && isStringPrefix(ident) && ident != "":
+				// The literal itself is skipped next.
+			default:
+				s.emit(identToken, ident, line)
+			}
+		case c == ':' && s.peek(1) == ':':
+			s.emit(punctToken, "::", s.line())
+			s.pos += 2
+		default:
+			s.emit(punctToken, string(c), s.line())
+			s.pos++
+		}
+		lineStart = false
+	}
+	return s.tokens
+}
+
+// isStringPrefix reports whether p is an encoding prefix of a C++ literal.
+func isStringPrefix(p string) bool {
+	return p == "" || p == "L" || p == "u" || p == "U" || p == "u8"
+}
+
+// scanDirective handles a preprocessor directive, emitting the header name
+// of an #include as a single token.
+func (s *scanner) scanDirective() {
+	s.emit(punctToken, "#", s.line())
+	s.pos++
+	for s.pos < len(s.src) && isSpace(s.src[s.pos]) {
+		s.pos++
+	}
+	line := s.line()
+	name := s.readIdent()
+	s.emit(identToken, name, line)
+	if name != "include" && name != "include_next" && name != "import" {
+		return
+	}
+	for s.pos < len(s.src) && isSpace(s.src[s.pos]) {
+		s.pos++
+	}
+	var end byte
+	switch s.peek(0) {
+	case '<':
+		end = '>'
+	case '"':
+		end = '"'
+	default:
+		// A macro naming the header; its tokens are scanned as code.
+		return
+	}
+	start := s.pos + 1
+	if i := strings.IndexAny(s.src[start:], string(end)+"\n"); i >= 0 && s.src[start+i] == end {
+		s.emit(headerToken, strings.TrimSpace(s.src[start:start+i]), line)
+		s.pos = start + i + 1
+	}
+}
+
+// skipRawString moves past a C++ raw string literal R"delim(...)delim".
+func (s *scanner) skipRawString() {
+	open := strings.IndexByte(s.src[s.pos:], '(')
+	if open < 0 {
+		s.skipQuoted('"')
+		return
+	}
+	delimiter := s.src[s.pos+1 : s.pos+open]
+	s.advance(open + 1)
+	s.skipUntil(")" + delimiter + "\"")
+}
+
+// scanPython tokenizes Python source.
+func scanPython(src string) []token {
+	s := newScanner(src, nil)
+	s.scanPythonCode(false)
+	return s.tokens
+}
+
+// scanPythonCode tokenizes Python code. Inside an f-string replacement field,
+// it stops after the closing brace that ends the field.
+func (s *scanner) scanPythonCode(inField bool) {
+	depth := 0
+	for s.pos < len(s.src) {
+		c := s.src[s.pos]
+		switch {
+		case c == '\n':
+			s.advance(1)
+		case isSpace(c) || c == '\\':
+			s.pos++
+		case c == '#':
+			s.skipUntil("\n")
+		case c == '"' || c == '\'':
+			s.skipPythonString("")
+		case isDigit(c) || (c == '.' && isDigit(s.peek(1))):
+			s.skipNumber()
+		case isIdentStart(c):
+			line := s.line()
+			ident := s.readIdent()
+			if (s.peek(0) == '"' || s.peek(0) == '\'') && isPythonStringPrefix(ident) {
+				s.skipPythonString(strings.ToLower(ident))
+			} else {
+				s.emit(identToken, ident, line)
+			}
+		default:
+			switch c {
+			case '(', '[', '{':
+				depth++
+			case ')', ']', '}':
+				if depth == 0 && inField && c == '}' {
+					s.pos++
+					return
+				}
+				depth--
+			}
+			s.emit(punctToken, string(c), s.line())
+			s.pos++
+		}
+	}
+}
+
+func isPythonStringPrefix(p string) bool {
+	switch strings.ToLower(p) {
+	case "r", "u", "b", "f", "br", "rb", "fr", "rf":
+		return true
+	}
+	return false
+}
+
+// skipPythonString moves past a string literal with the given lowercase
+// prefix. The replacement fields of f-strings are scanned as code.
+func (s *scanner) skipPythonString(prefix string) {
+	quote := s.src[s.pos : s.pos+1]
+	if strings.HasPrefix(s.src[s.pos:], strings.Repeat(quote, 3)) {
+		quote = strings.Repeat(quote, 3)
+	}
+	formatted := strings.Contains(prefix, "f")
+	s.pos += len(quote)
+	for s.pos < len(s.src) {
+		switch {
+		case strings.HasPrefix(s.src[s.pos:], quote):
+			s.pos += len(quote)
+			return
+		case s.src[s.pos] == '\\':
+			// Even raw strings cannot end in an escaped quote.
+			s.advance(2)
+		case s.src[s.pos] == '\n' && len(quote) == 1:
+			return
+		case formatted && strings.HasPrefix(s.src[s.pos:], "{{"):
+			s.pos += 2
+		case formatted && s.src[s.pos] == '{':
+			s.pos++
+			s.scanPythonCode(true)
+		default:
+			s.advance(1)
+		}
+	}
+}
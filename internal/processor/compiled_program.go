@@ -0,0 +1,118 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"judging-service/containers"
+	"judging-service/internal/models"
+	"judging-service/internal/service"
+	"log"
+	"time"
+)
+
+// compiledProgram is a submission compiled once in a compile container, ready
+// to run in fresh run containers that hold nothing but its artifacts.
+type compiledProgram struct {
+	artifacts   map[string][]byte
+	fileName    string
+	runCommand  string
+	compileTime time.Duration
+}
+
+// compileProgram compiles the code in a compile container of the language and
+// collects the artifacts. It is used for languages with a separate run sandbox.
+// Artifacts found in the pool's artifact cache are used without compiling.
+func compileProgram(m *containers.ContainersPoolManger, code string, codeLanguage int, options models.SubmissionOptions, resourceLimit models.ResourceLimit) (compiledProgram, error) {
+	var cacheKey string
+	var cacheable bool
+	if spec, ok := m.Languages.Lookup(codeLanguage); ok {
+		runner := service.RegistryRunLangInterface{Spec: spec, Limit: resourceLimit, Options: options}
+		cacheKey, cacheable = artifactCacheKey(m, runner, code)
+		if cacheable {
+			if artifacts, hit := m.ArtifactCache.Get(cacheKey); hit {
+				log.Printf("Step 'Compile' skipped, artifacts found in cache")
+				fileName := runner.SourceFileName(code)
+				return compiledProgram{artifacts: artifacts, fileName: fileName, runCommand: runner.RunCommandLine(fileName)}, nil
+			}
+		}
+	}
+
+	doc, exec, spec, err := m.GetCompileContainer(codeLanguage, resourceLimit, options)
+	if err != nil {
+		return compiledProgram{}, fmt.Errorf("failed to get container: %w", err)
+	}
+	defer m.FreeContainer(doc)
+
+	fileName, err := exec.CopyCodeToFile(doc, code)
+	if err != nil {
+		return compiledProgram{}, fmt.Errorf("failed to copy code: %w", err)
+	}
+
+	compileStart := time.Now()
+	runCommand, err := runCompileStep(spec.CompileTimeout(), func(ctx context.Context) (string, error) {
+		return exec.CompileCode(doc, fileName, ctx)
+	})
+	program := compiledProgram{fileName: fileName, runCommand: runCommand, compileTime: time.Since(compileStart)}
+	if err != nil {
+		return program, fmt.Errorf("compilation failed: %w", err)
+	}
+	log.Printf("Step 'Compile' completed in %v", program.compileTime)
+
+	if program.artifacts, err = exec.ReadArtifacts(doc, fileName); err != nil {
+		return program, fmt.Errorf("failed to collect artifacts: %w", err)
+	}
+	if cacheable {
+		if err := m.ArtifactCache.Put(cacheKey, program.artifacts); err != nil {
+			log.Printf("Failed to cache artifacts: %v", err)
+		}
+	}
+	return program, nil
+}
+
+// cacheArtifacts collects the artifacts just compiled in a container and keeps
+// them in the pool's artifact cache. Failing to is not the submission's fault,
+// so it is only logged.
+func cacheArtifacts(m *containers.ContainersPoolManger, exec models.LangContainer, doc *models.Container, fileName string, cacheKey string) {
+	artifacts, err := exec.ReadArtifacts(doc, fileName)
+	if err == nil {
+		err = m.ArtifactCache.Put(cacheKey, artifacts)
+	}
+	if err != nil {
+		log.Printf("Failed to cache artifacts: %v", err)
+	}
+}
+
+// artifactCacheKey is the pool's artifact cache key for compiling the code
+// with the runner's language and options; ok is false when it is not cached.
+func artifactCacheKey(m *containers.ContainersPoolManger, runner service.RegistryRunLangInterface, code string) (key string, ok bool) {
+	sources, err := runner.SourceFiles(code)
+	if err != nil {
+		return "", false
+	}
+	compileCommand := runner.CompileCommandLine(runner.SourceFileName(code))
+	return m.ArtifactCacheKey(context.Background(), runner.Spec, runner.Options, compileCommand, sources)
+}
+
+// runCompiledTestCase runs a compiled program against one test case in a fresh run container.
+func runCompiledTestCase(m *containers.ContainersPoolManger, program compiledProgram, testcase string, codeLanguage int, options models.SubmissionOptions, resourceLimit models.ResourceLimit) (*string, error) {
+	doc, exec, spec, err := m.GetRunContainer(codeLanguage, resourceLimit, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get container: %w", err)
+	}
+	defer m.FreeContainer(doc)
+
+	if err := exec.CopyArtifacts(doc, program.artifacts); err != nil {
+		return nil, fmt.Errorf("failed to copy artifacts: %w", err)
+	}
+
+	runStart := time.Now()
+	runTimeout := time.Duration(float64(resourceLimit.TimeLimitInSeconds) * spec.TimeMultiplier * float64(time.Second))
+	output, err := runStepWithTimeout(runTimeout, func(ctx context.Context) (string, error) {
+		return exec.RunTestCases(doc, program.fileName, testcase, program.runCommand, ctx)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("execution failed: %w", err)
+	}
+	log.Printf("Step 'Run' completed in %v", time.Since(runStart))
+	return &output, nil
+}
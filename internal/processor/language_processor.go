@@ -8,7 +8,12 @@ import (
 	"judging-service/containers"
 	customErrors "judging-service/internal/customErrors"
 	"judging-service/internal/models"
+	"judging-service/internal/policy"
+	"judging-service/internal/project"
+	"judging-service/internal/service"
 	"log"
+	"maps"
+	"slices"
 	"strings"
 	"time"
 )
@@ -18,25 +23,105 @@ func RunCodeWithTestcases(
 	submission Dtos.SubmissionQueueDto,
 ) (models.JudgingResult, error) {
 
+	limit := models.ResourceLimit{
+		MemoryLimitInMB:    submission.MemoryLimit,
+		TimeLimitInSeconds: submission.TimeLimit,
+		CPU:                1,
+	}
+	spec, known := m.Languages.Lookup(submission.Language)
+	options := submission.Options(spec.Family)
+
+	// Projects and I/O files are checked, and policy rules applied, before any
+	// container is used.
+	submitted, err := submission.Project()
+	var projectErr *project.Error
+	if errors.As(err, &projectErr) {
+		err = &customErrors.InvalidSubmissionError{Reason: projectErr.Reason}
+	}
+	if err == nil && submitted != nil {
+		options.Project = submitted
+		entry := service.RegistryRunLangInterface{Spec: spec, Options: options}.SourceFileName(submission.Code)
+		if _, ok := submitted.Files[entry]; known && !ok {
+			err = &customErrors.InvalidSubmissionError{Reason: fmt.Sprintf("the project has no entry file %s", entry)}
+		}
+	}
+	if err == nil && known && spec.DetectMainClass && submitted == nil {
+		if pkg := service.JavaPackage(submission.Code); pkg != "" {
+			err = &customErrors.InvalidSubmissionError{Reason: fmt.Sprintf("the code declares package %s; submit it in the unnamed package", pkg)}
+		}
+	}
+	if err == nil && options.FileIO != nil {
+		if ioErr := (service.RegistryRunLangInterface{Spec: spec, Limit: limit, Options: options}).ValidateFileIO(submission.Code); ioErr != nil {
+			err = &customErrors.InvalidSubmissionError{Reason: ioErr.Error()}
+		}
+	}
+	if err == nil {
+		err = checkPolicy(spec, known, submission, submitted)
+	}
+	if err != nil {
+		return models.JudgingResult{
+			SubmissionId: submission.SubmissionId,
+			Verdict:      3,
+			Reason:       failureReason(err),
+			ReasonDetail: failureDetail(err),
+			IsErrorExist: true,
+		}, fmt.Errorf("submission refused: %w", err)
+	}
+	compileCommand := effectiveCompileCommand(spec, known, submission.Code, options, limit)
+
+	// By default every test case compiles and runs in a fresh container.
+	// Languages with a separate run sandbox compile once, then run each test
+	// case in a fresh minimal container holding only the compiled program.
+	// Diagnostic builds need the runtime libraries of the compile image, so
+	// they always run where they were compiled.
+	runTest := func(input string) (*string, time.Duration, error) {
+		return RuntestCase(m, submission.Code, input, submission.Language, options, limit)
+	}
+	if known && spec.SeparateRunSandbox() && !options.Diagnostics {
+		program, compileErr := compileProgram(m, submission.Code, submission.Language, options, limit)
+		runTest = func(input string) (*string, time.Duration, error) {
+			if compileErr != nil {
+				return nil, program.compileTime, compileErr
+			}
+			output, err := runCompiledTestCase(m, program, input, submission.Language, options, limit)
+			return output, program.compileTime, err
+		}
+	}
+
+	// The first test case's compile time is reported.
+	var compileTime time.Duration
 	outputs := make([]models.TestCaseOutput, 0, len(submission.InputTests))
 	for i, testCase := range submission.InputTests {
-		testOutput, err := RuntestCase(m, submission.Code, testCase.Input, submission.Language, models.ResourceLimit{
-			MemoryLimitInMB:    submission.MemoryLimit,
-			TimeLimitInSeconds: submission.TimeLimit,
-			CPU:                1,
-		})
+		testOutput, testCompileTime, err := runTest(testCase.Input)
+		if i == 0 {
+			compileTime = testCompileTime
+		}
 		if err != nil {
 			var verdict int = 3
 
 			if strings.Contains(err.Error(), "Time Limit Exceeded") {
 				verdict = 2
 			}
+			var compilationOutput, diagnosticReport string
+			var compilationErr *customErrors.CompilationError
+			var runtimeErr *customErrors.RuntimeError
+			if errors.As(err, &compilationErr) {
+				compilationOutput = compilationErr.Output
+			} else if errors.As(err, &runtimeErr) {
+				diagnosticReport = runtimeErr.Report
+			}
 			return models.JudgingResult{
-				SubmissionId: submission.SubmissionId,
-				Verdict:      verdict,
-				Outputs:      nil,
-				IsErrorExist: true,
-				FallingTest:  i + 1,
+				SubmissionId:      submission.SubmissionId,
+				Verdict:           verdict,
+				Reason:            failureReason(err),
+				ReasonDetail:      failureDetail(err),
+				Outputs:           nil,
+				IsErrorExist:      true,
+				FallingTest:       i + 1,
+				CompileCommand:    compileCommand,
+				CompileTimeMs:     compileTime.Milliseconds(),
+				CompilationOutput: compilationOutput,
+				DiagnosticReport:  diagnosticReport,
 			}, fmt.Errorf("testcase #%d failed: %w", i+1, err)
 		}
 
@@ -48,47 +133,193 @@ func RunCodeWithTestcases(
 		}
 	}
 	return models.JudgingResult{
-		SubmissionId: submission.SubmissionId,
-		Verdict:      0,
-		IsErrorExist: false,
-		Outputs:      outputs,
-		FallingTest:  0,
+		SubmissionId:   submission.SubmissionId,
+		Verdict:        0,
+		Reason:         models.Passed,
+		IsErrorExist:   false,
+		Outputs:        outputs,
+		FallingTest:    0,
+		CompileCommand: compileCommand,
+		CompileTimeMs:  compileTime.Milliseconds(),
 	}, nil
 }
-func RuntestCase(m *containers.ContainersPoolManger, code string, testcase string, codeLanguage int, resourceLimit models.ResourceLimit) (*string, error) {
+
+// failureReason tells apart the failures the verdict groups together; it is
+// empty for failures with no more specific reason.
+func failureReason(err error) models.ProblemState {
+	var compilationTimeout *customErrors.CompilationTimeoutError
+	var compilationErr *customErrors.CompilationError
+	var tle *customErrors.TimeLimitExceededError
+	var runtimeErr *customErrors.RuntimeError
+	var restricted *customErrors.RestrictedFunctionError
+	var policyErr *customErrors.PolicyViolationError
+	var invalid *customErrors.InvalidSubmissionError
+	switch {
+	case errors.As(err, &compilationTimeout):
+		return models.CompilationTimeout
+	case errors.As(err, &compilationErr):
+		return models.CompilationError
+	case errors.As(err, &tle):
+		return models.TimeLimitExceeded
+	case errors.As(err, &runtimeErr):
+		return models.RuntimeError
+	case errors.As(err, &restricted):
+		return models.RestrictedFunction
+	case errors.As(err, &policyErr):
+		return models.PolicyViolation
+	case errors.As(err, &invalid):
+		return models.InvalidSubmission
+	}
+	return ""
+}
+
+// failureDetail describes the failures whose reason alone does not say what
+// the submission did wrong.
+func failureDetail(err error) string {
+	var restricted *customErrors.RestrictedFunctionError
+	var policyErr *customErrors.PolicyViolationError
+	var invalid *customErrors.InvalidSubmissionError
+	switch {
+	case errors.As(err, &restricted):
+		return restricted.Error()
+	case errors.As(err, &policyErr):
+		return policyErr.Error()
+	case errors.As(err, &invalid):
+		return invalid.Error()
+	}
+	return ""
+}
+
+// maxReportedViolations keeps the policy verdict readable.
+const maxReportedViolations = 10
+
+// checkPolicy checks the source, or every source file of the project, against
+// the problem's rules for the submission's language family, if it has any.
+func checkPolicy(spec models.LanguageSpec, known bool, submission Dtos.SubmissionQueueDto, submitted *models.Project) error {
+	if !known {
+		return nil
+	}
+	rules, ok := submission.Policy[string(spec.Family)]
+	if !ok {
+		return nil
+	}
+	syntax := policy.Syntax(spec.Syntax)
+	sources := map[string]string{"": submission.Code}
+	if submitted != nil {
+		sources = make(map[string]string)
+		for name, content := range submitted.Files {
+			if syntax.Covers(name) {
+				sources[name] = content
+			}
+		}
+	}
+	var violations []policy.Violation
+	for _, name := range slices.Sorted(maps.Keys(sources)) {
+		found, err := policy.Check(syntax, sources[name], rules)
+		if err != nil {
+			return fmt.Errorf("language %q: %w", spec.Key, err)
+		}
+		for _, violation := range found {
+			violation.File = name
+			violations = append(violations, violation)
+		}
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	policyErr := &customErrors.PolicyViolationError{}
+	for _, violation := range violations[:min(len(violations), maxReportedViolations)] {
+		policyErr.Violations = append(policyErr.Violations, violation.String())
+	}
+	return policyErr
+}
+
+// effectiveCompileCommand renders the compile command the submission is built
+// with, flags included, so it can be reported alongside the verdict.
+func effectiveCompileCommand(spec models.LanguageSpec, known bool, code string, options models.SubmissionOptions, limit models.ResourceLimit) string {
+	if !known {
+		return ""
+	}
+	runner := service.RegistryRunLangInterface{Spec: spec, Limit: limit, Options: options}
+	return strings.Join(runner.CompileCommandLine(runner.SourceFileName(code)), " ")
+}
+
+// RuntestCase compiles and runs the code against one test case in a fresh
+// container. It also returns how long compilation took, even when it failed.
+// Artifacts found in the pool's artifact cache are installed without
+// compiling, so only the first test case of a submission compiles it.
+func RuntestCase(m *containers.ContainersPoolManger, code string, testcase string, codeLanguage int, options models.SubmissionOptions, resourceLimit models.ResourceLimit) (*string, time.Duration, error) {
 	overallStart := time.Now()
 
-	doc, exec, _, err := m.GetContainerWithLimits(codeLanguage, resourceLimit)
+	doc, exec, spec, err := m.GetContainerWithLimits(codeLanguage, resourceLimit, options)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get container: %w", err)
+		return nil, 0, fmt.Errorf("failed to get container: %w", err)
 	}
 	defer m.FreeContainer(doc)
 
 	fileName, err := exec.CopyCodeToFile(doc, code)
 	if err != nil {
-		return nil, fmt.Errorf("failed to copy code: %w", err)
+		return nil, 0, fmt.Errorf("failed to copy code: %w", err)
 	}
 
-	compileStart := time.Now()
-	compileCommand, err := runStepWithTimeout(10*time.Second, func(ctx context.Context) (string, error) {
-		return exec.CompileCode(doc, fileName, ctx)
-	})
-	if err != nil {
-		return nil, fmt.Errorf("compilation failed: %w", err)
+	cacheKey, cacheable := artifactCacheKey(m, service.RegistryRunLangInterface{Spec: spec, Limit: resourceLimit, Options: options}, code)
+	var cached map[string][]byte
+	if cacheable {
+		cached, _ = m.ArtifactCache.Get(cacheKey)
+	}
+
+	var compileCommand string
+	var compileTime time.Duration
+	if cached != nil {
+		compileCommand, err = runCompileStep(spec.CompileTimeout(), func(ctx context.Context) (string, error) {
+			return exec.InstallArtifacts(doc, fileName, cached, ctx)
+		})
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to install cached artifacts: %w", err)
+		}
+		log.Printf("Step 'Compile' skipped, artifacts found in cache")
+	} else {
+		compileStart := time.Now()
+		compileCommand, err = runCompileStep(spec.CompileTimeout(), func(ctx context.Context) (string, error) {
+			return exec.CompileCode(doc, fileName, ctx)
+		})
+		compileTime = time.Since(compileStart)
+		if err != nil {
+			return nil, compileTime, fmt.Errorf("compilation failed: %w", err)
+		}
+		log.Printf("Step 'Compile' completed in %v", compileTime)
+		if cacheable {
+			cacheArtifacts(m, exec, doc, fileName, cacheKey)
+		}
+	}
+	if err := m.RestrictToRunLimits(doc, spec, resourceLimit); err != nil {
+		return nil, compileTime, fmt.Errorf("failed to apply run limits: %w", err)
 	}
-	log.Printf("Step 'Compile' completed in %v", time.Since(compileStart))
 
 	runStart := time.Now()
-	output, err := runStepWithTimeout(time.Duration(resourceLimit.TimeLimitInSeconds)*time.Second, func(ctx context.Context) (string, error) {
-		return exec.RunTestCases(doc, testcase, compileCommand, ctx)
+	// Slower runtimes get proportionally more time, as declared in the language registry.
+	runTimeout := time.Duration(float64(resourceLimit.TimeLimitInSeconds) * spec.TimeMultiplier * float64(time.Second))
+	output, err := runStepWithTimeout(runTimeout, func(ctx context.Context) (string, error) {
+		return exec.RunTestCases(doc, fileName, testcase, compileCommand, ctx)
 	})
 	if err != nil {
-		return nil, fmt.Errorf("execution failed: %w", err)
+		return nil, compileTime, fmt.Errorf("execution failed: %w", err)
 	}
 	log.Printf("Step 'Run' completed in %v", time.Since(runStart))
 
 	fmt.Printf(" Total Execution Time: %v\n", time.Since(overallStart))
-	return &output, nil
+	return &output, compileTime, nil
+}
+
+// runCompileStep is runStepWithTimeout for compilers, whose timeout is a
+// compilation timeout rather than the program exceeding the time limit.
+func runCompileStep(timeout time.Duration, task func(ctx context.Context) (string, error)) (string, error) {
+	output, err := runStepWithTimeout(timeout, task)
+	var tle *customErrors.TimeLimitExceededError
+	if errors.As(err, &tle) {
+		return "", &customErrors.CompilationTimeoutError{Limit: tle.Limit}
+	}
+	return output, err
 }
 
 func runStepWithTimeout(timeout time.Duration, task func(ctx context.Context) (string, error)) (string, error) {
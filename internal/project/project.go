@@ -0,0 +1,199 @@
+// Package project turns multi-file submissions, sent as a file map or as a
+// zip or tar archive, into the files written to the workspace, enforcing the
+// problem's limits on how many files there are and how large they are.
+package project
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"unicode"
+)
+
+// ReservedDir is the workspace directory the judge keeps its own files in,
+// e.g. the Python import hook; no project file may live there.
+const ReservedDir = ".judge"
+
+// Limits bound a project's size.
+type Limits struct {
+	// MaxFiles is the most files a project may hold.
+	MaxFiles int
+	// MaxBytes is the most bytes all its files may hold together, uncompressed.
+	MaxBytes int64
+}
+
+// DefaultLimits apply when a problem sets no limits of its own.
+var DefaultLimits = Limits{MaxFiles: 64, MaxBytes: 1 << 20}
+
+// Error is a project that cannot be accepted, e.g. one over its limits or
+// with a file outside the project directory.
+type Error struct {
+	Reason string
+}
+
+func (e *Error) Error() string {
+	return e.Reason
+}
+
+func invalid(format string, args ...any) error {
+	return &Error{Reason: fmt.Sprintf(format, args...)}
+}
+
+// Load checks a file map against limits and returns it with its paths cleaned.
+func Load(files map[string]string, limits Limits) (map[string]string, error) {
+	if len(files) == 0 {
+		return nil, invalid("the project has no files")
+	}
+	if len(files) > limits.MaxFiles {
+		return nil, invalid("the project has %d files, the limit is %d", len(files), limits.MaxFiles)
+	}
+	cleaned := make(map[string]string, len(files))
+	var total int64
+	for name, content := range files {
+		clean, err := CleanPath(name)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := cleaned[clean]; ok {
+			return nil, invalid("%s appears twice in the project", clean)
+		}
+		cleaned[clean] = content
+		total += int64(len(content))
+	}
+	if total > limits.MaxBytes {
+		return nil, invalid("the project is %d bytes, the limit is %d", total, limits.MaxBytes)
+	}
+	return cleaned, nil
+}
+
+// Unpack reads the files of a zip, tar or gzip-compressed tar archive, keyed
+// by their path in it. Directories are skipped; links and other special
+// files are refused. Reading stops as soon as the archive exceeds limits, so
+// a small archive that inflates to a huge one is never read in full.
+func Unpack(archive []byte, limits Limits) (map[string]string, error) {
+	u := unpacker{limits: limits, files: make(map[string]string)}
+	var err error
+	switch {
+	case bytes.HasPrefix(archive, []byte("PK\x03\x04")):
+		err = u.zip(archive)
+	case bytes.HasPrefix(archive, []byte{0x1f, 0x8b}):
+		var gz *gzip.Reader
+		if gz, err = gzip.NewReader(bytes.NewReader(archive)); err == nil {
+			err = u.tar(gz)
+		}
+	default:
+		err = u.tar(bytes.NewReader(archive))
+	}
+	if err != nil {
+		var projectErr *Error
+		if errors.As(err, &projectErr) {
+			return nil, err
+		}
+		return nil, invalid("the archive cannot be read: %v", err)
+	}
+	if len(u.files) == 0 {
+		return nil, invalid("the project has no files")
+	}
+	return u.files, nil
+}
+
+type unpacker struct {
+	limits Limits
+	files  map[string]string
+	total  int64
+}
+
+func (u *unpacker) zip(archive []byte) error {
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return err
+	}
+	for _, file := range reader.File {
+		mode := file.Mode()
+		if mode.IsDir() {
+			continue
+		}
+		if !mode.IsRegular() {
+			return invalid("%s is not a regular file", file.Name)
+		}
+		content, err := file.Open()
+		if err != nil {
+			return err
+		}
+		err = u.add(file.Name, content)
+		content.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (u *unpacker) tar(r io.Reader) error {
+	reader := tar.NewReader(r)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		switch header.Typeflag {
+		case tar.TypeDir, tar.TypeXGlobalHeader:
+			continue
+		case tar.TypeReg:
+		default:
+			return invalid("%s is not a regular file", header.Name)
+		}
+		if err := u.add(header.Name, reader); err != nil {
+			return err
+		}
+	}
+}
+
+// add reads one file, never more than the bytes left under the limit.
+func (u *unpacker) add(name string, r io.Reader) error {
+	clean, err := CleanPath(name)
+	if err != nil {
+		return err
+	}
+	if _, ok := u.files[clean]; ok {
+		return invalid("%s appears twice in the archive", clean)
+	}
+	if len(u.files) == u.limits.MaxFiles {
+		return invalid("the project has more than %d files", u.limits.MaxFiles)
+	}
+	content, err := io.ReadAll(io.LimitReader(r, u.limits.MaxBytes-u.total+1))
+	if err != nil {
+		return err
+	}
+	u.total += int64(len(content))
+	if u.total > u.limits.MaxBytes {
+		return invalid("the project is more than %d bytes", u.limits.MaxBytes)
+	}
+	u.files[clean] = string(content)
+	return nil
+}
+
+// CleanPath normalises a project path, refusing any that leaves the project,
+// lands in the judge's directory or holds whitespace, which the run command
+// line cannot carry.
+func CleanPath(name string) (string, error) {
+	clean := path.Clean(strings.TrimPrefix(name, "./"))
+	if name == "" || path.IsAbs(name) || clean == "." || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", invalid("%q is not a path inside the project", name)
+	}
+	if clean == ReservedDir || strings.HasPrefix(clean, ReservedDir+"/") {
+		return "", invalid("%q is in the judge's %s directory", name, ReservedDir)
+	}
+	if strings.ContainsFunc(clean, unicode.IsSpace) {
+		return "", invalid("%q has whitespace in its name", name)
+	}
+	return clean, nil
+}
@@ -0,0 +1,165 @@
+package registry
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"judging-service/internal/models"
+	"judging-service/internal/policy"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+//go:embed languages.json
+var defaultLanguages []byte
+
+// Registry holds the languages the judge accepts, keyed by the numeric id
+// submissions refer to them with.
+type Registry struct {
+	byID map[int]models.LanguageSpec
+}
+
+// Default returns the registry bundled with the service.
+func Default() *Registry {
+	r, err := Parse(defaultLanguages)
+	if err != nil {
+		panic(fmt.Sprintf("bundled languages.json is invalid: %v", err))
+	}
+	return r
+}
+
+// Load reads a registry from a JSON file with the same layout as languages.json.
+func Load(path string) (*Registry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read language registry: %w", err)
+	}
+	r, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid language registry %s: %w", path, err)
+	}
+	return r, nil
+}
+
+func Parse(data []byte) (*Registry, error) {
+	var specs []models.LanguageSpec
+	if err := json.Unmarshal(data, &specs); err != nil {
+		return nil, err
+	}
+
+	r := &Registry{byID: make(map[int]models.LanguageSpec, len(specs))}
+	keys := make(map[models.Language]bool, len(specs))
+	for _, spec := range specs {
+		if err := validate(spec); err != nil {
+			return nil, err
+		}
+		if _, ok := r.byID[spec.ID]; ok {
+			return nil, fmt.Errorf("duplicate language id %d", spec.ID)
+		}
+		if keys[spec.Key] {
+			return nil, fmt.Errorf("duplicate language key %q", spec.Key)
+		}
+		if spec.TimeMultiplier == 0 {
+			spec.TimeMultiplier = 1
+		}
+		if spec.Family == "" {
+			spec.Family = spec.Key
+		}
+		r.byID[spec.ID] = spec
+		keys[spec.Key] = true
+	}
+	return r, nil
+}
+
+func validate(spec models.LanguageSpec) error {
+	switch {
+	case spec.Key == "":
+		return fmt.Errorf("language %d has no key", spec.ID)
+	case spec.Image == "":
+		return fmt.Errorf("language %q has no image", spec.Key)
+	case spec.SourceFile == "":
+		return fmt.Errorf("language %q has no source file", spec.Key)
+	case len(spec.RunCommand) == 0:
+		return fmt.Errorf("language %q has no run command", spec.Key)
+	case spec.TimeMultiplier < 0:
+		return fmt.Errorf("language %q has a negative time multiplier", spec.Key)
+	case spec.RunImage != "" && len(spec.Artifacts) == 0:
+		return fmt.Errorf("language %q has a run image but no artifacts to run in it", spec.Key)
+	case len(spec.Artifacts) > 0 && len(spec.CompileCommand) == 0:
+		return fmt.Errorf("language %q has artifacts but no compile command", spec.Key)
+	case spec.RunImage != "" && len(spec.CompileCommand) == 0:
+		return fmt.Errorf("language %q has a run image but no compile command", spec.Key)
+	case len(spec.DefaultFlags) > 0 && !slices.Contains(spec.CompileCommand, "{flags}"):
+		return fmt.Errorf("language %q has default flags but no {flags} in its compile command", spec.Key)
+	case len(spec.RunImageFlags) > 0 && (spec.RunImage == "" || !slices.Contains(spec.CompileCommand, "{flags}")):
+		return fmt.Errorf("language %q has run image flags but no run image or no {flags} in its compile command", spec.Key)
+	case spec.Syntax != "" && !policy.Supported(policy.Syntax(spec.Syntax)):
+		return fmt.Errorf("language %q has unknown syntax %q", spec.Key, spec.Syntax)
+	case spec.Diagnostics != nil && len(spec.Diagnostics.Flags) > 0 && !slices.Contains(spec.CompileCommand, "{flags}"):
+		return fmt.Errorf("language %q has diagnostic flags but no {flags} in its compile command", spec.Key)
+	}
+	return nil
+}
+
+func (r *Registry) Lookup(id int) (models.LanguageSpec, bool) {
+	spec, ok := r.byID[id]
+	return spec, ok
+}
+
+// All lists every language variant ordered by id.
+func (r *Registry) All() []models.LanguageSpec {
+	specs := make([]models.LanguageSpec, 0, len(r.byID))
+	for _, spec := range r.byID {
+		specs = append(specs, spec)
+	}
+	sort.Slice(specs, func(i, j int) bool { return specs[i].ID < specs[j].ID })
+	return specs
+}
+
+var imageLine = regexp.MustCompile(`^(\s*)"(image|runImage)": "([^"]*)"(,?)\s*$`)
+var imageDigestLine = regexp.MustCompile(`^\s*"(imageDigest|runImageDigest)": "[^"]*",?\s*$`)
+
+// PinDigests rewrites a registry file so every image, run image or diagnostic
+// image that appears in digests is pinned to that digest, written after it as
+// imageDigest or runImageDigest. It edits the file line by line rather than
+// re-encoding it, so the hand-maintained layout survives.
+func PinDigests(data []byte, digests map[string]string) ([]byte, error) {
+	lines := strings.Split(string(data), "\n")
+	out := make([]string, 0, len(lines)+len(digests))
+	for i := 0; i < len(lines); i++ {
+		match := imageLine.FindStringSubmatch(lines[i])
+		digest, pin := "", false
+		if match != nil {
+			digest, pin = digests[match[3]]
+		}
+		if !pin {
+			out = append(out, lines[i])
+			continue
+		}
+		indent, key, image, comma := match[1], match[2], match[3], match[4]
+		out = append(out, fmt.Sprintf(`%s%q: %q,`, indent, key, image))
+		out = append(out, fmt.Sprintf(`%s%q: %q%s`, indent, key+"Digest", digest, comma))
+		if next := i + 1; next < len(lines) {
+			if m := imageDigestLine.FindStringSubmatch(lines[next]); m != nil && m[1] == key+"Digest" {
+				i++
+			}
+		}
+	}
+	pinned := []byte(strings.Join(out, "\n"))
+
+	r, err := Parse(pinned)
+	if err != nil {
+		return nil, fmt.Errorf("pinned registry is invalid: %w", err)
+	}
+	for _, spec := range r.All() {
+		for _, image := range spec.PinnedImages() {
+			if digest, ok := digests[string(image.Name)]; ok && image.Digest != digest {
+				return nil, fmt.Errorf("failed to pin %s for %q: the image is not written on a line of its own", image.Name, spec.Key)
+			}
+		}
+	}
+	return pinned, nil
+}
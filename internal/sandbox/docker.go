@@ -0,0 +1,427 @@
+package sandbox
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/build"
+	"github.com/docker/docker/api/types/container"
+	dockerimage "github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/client"
+)
+
+// DockerRuntime runs every sandbox as a Docker container through one shared client.
+type DockerRuntime struct {
+	cli            *client.Client
+	requestTimeout time.Duration
+}
+
+// NewDockerRuntime connects to the daemon described by settings.
+func NewDockerRuntime(settings DockerSettings) (*DockerRuntime, error) {
+	cli, err := NewDockerClient(settings)
+	if err != nil {
+		return nil, err
+	}
+	return NewDockerRuntimeWithClient(cli, settings.RequestTimeout), nil
+}
+
+// NewDockerRuntimeWithClient wraps an existing client. The runtime takes
+// ownership of it and closes it in Close.
+func NewDockerRuntimeWithClient(cli *client.Client, requestTimeout time.Duration) *DockerRuntime {
+	if requestTimeout <= 0 {
+		requestTimeout = defaultDockerRequestTimeout
+	}
+	return &DockerRuntime{cli: cli, requestTimeout: requestTimeout}
+}
+
+func (d *DockerRuntime) Create(ctx context.Context, spec Spec) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, d.requestTimeout)
+	defer cancel()
+
+	containerConfig := &container.Config{
+		Image:      spec.Image,
+		Tty:        false,
+		Cmd:        []string{"sleep", "600"},
+		WorkingDir: spec.WorkingDir,
+	}
+
+	// CPUCount is only honored on Windows hosts, so the CPU limit is
+	// expressed as NanoCPUs (a CFS quota) and optional cpuset pinning.
+	hostConfig := &container.HostConfig{
+		Resources: container.Resources{
+			Memory:     int64(spec.MemoryLimitInMB) * 1024 * 1024,
+			NanoCPUs:   spec.NanoCPUs,
+			CpusetCpus: spec.CpusetCpus,
+		},
+	}
+
+	resp, err := d.cli.ContainerCreate(ctx, containerConfig, hostConfig, nil, nil, "")
+	if err != nil {
+		return "", fmt.Errorf("failed to create container: %v", err)
+	}
+
+	if err := d.cli.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
+		_ = d.Remove(context.Background(), resp.ID)
+		return "", fmt.Errorf("failed to start container: %v", err)
+	}
+	return resp.ID, nil
+}
+
+func (d *DockerRuntime) Exec(ctx context.Context, id string, req ExecRequest) (ExecResult, error) {
+	execConfig := container.ExecOptions{
+		Cmd:          req.Cmd,
+		Env:          req.Env,
+		AttachStdin:  req.Stdin != nil,
+		AttachStdout: true,
+		AttachStderr: true,
+		WorkingDir:   req.WorkingDir,
+	}
+
+	execResp, err := d.cli.ContainerExecCreate(ctx, id, execConfig)
+	if err != nil {
+		return ExecResult{}, fmt.Errorf("failed to create exec: %w", err)
+	}
+	attachResp, err := d.cli.ContainerExecAttach(ctx, execResp.ID, container.ExecStartOptions{})
+	if err != nil {
+		return ExecResult{}, fmt.Errorf("failed to attach to exec: %w", err)
+	}
+	defer attachResp.Close()
+
+	if req.Stdin != nil {
+		go func() {
+			defer attachResp.CloseWrite()
+			if _, err := attachResp.Conn.Write(req.Stdin); err != nil {
+				fmt.Printf("Warning: failed to write exec input: %v\n", err)
+			}
+		}()
+	}
+
+	output, err := io.ReadAll(ctxReader(ctx, attachResp.Reader))
+	if err != nil {
+		return ExecResult{}, err
+	}
+	stdout, stderr := demultiplexDockerOutput(output)
+
+	inspect, err := d.cli.ContainerExecInspect(ctx, execResp.ID)
+	if err != nil {
+		return ExecResult{}, fmt.Errorf("failed to inspect exec: %w", err)
+	}
+	return ExecResult{Stdout: stdout, Stderr: stderr, ExitCode: inspect.ExitCode}, nil
+}
+
+func (d *DockerRuntime) CopyFiles(ctx context.Context, id string, dir string, files map[string][]byte, mode fs.FileMode) error {
+	tarData, err := createTarArchiveFromMemory(files, mode)
+	if err != nil {
+		return fmt.Errorf("failed to create tar archive: %v", err)
+	}
+	if err := d.cli.CopyToContainer(ctx, id, dir, tarData, container.CopyToContainerOptions{}); err != nil {
+		return fmt.Errorf("failed to copy files to container: %v", err)
+	}
+	return nil
+}
+
+func (d *DockerRuntime) ReadFile(ctx context.Context, id string, path string) ([]byte, error) {
+	reader, _, err := d.cli.CopyFromContainer(ctx, id, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy %s from container: %v", path, err)
+	}
+	defer reader.Close()
+
+	tr := tar.NewReader(reader)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("%s is not a regular file", path)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tar stream: %v", err)
+		}
+		if header.Typeflag == tar.TypeReg {
+			return io.ReadAll(tr)
+		}
+	}
+}
+
+func (d *DockerRuntime) ListFiles(ctx context.Context, id string, dir string) ([]string, error) {
+	reader, _, err := d.cli.CopyFromContainer(ctx, id, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy %s from container: %v", dir, err)
+	}
+	defer reader.Close()
+
+	// The archive holds dir itself, named after its last element, and everything below it.
+	var names []string
+	tr := tar.NewReader(reader)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return names, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tar stream: %v", err)
+		}
+		if _, name, nested := strings.Cut(header.Name, "/"); header.Typeflag == tar.TypeReg && nested {
+			names = append(names, name)
+		}
+	}
+}
+
+func (d *DockerRuntime) SetMemoryLimit(ctx context.Context, id string, memoryLimitInMB int) error {
+	ctx, cancel := context.WithTimeout(ctx, d.requestTimeout)
+	defer cancel()
+	memory := int64(memoryLimitInMB) * 1024 * 1024
+	// Keep the swap allowance Create implies: as much swap as memory.
+	_, err := d.cli.ContainerUpdate(ctx, id, container.UpdateConfig{
+		Resources: container.Resources{Memory: memory, MemorySwap: 2 * memory},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update container memory: %v", err)
+	}
+	return nil
+}
+
+func (d *DockerRuntime) Inspect(ctx context.Context, id string) (State, error) {
+	resp, err := d.cli.ContainerInspect(ctx, id)
+	if err != nil {
+		return State{}, fmt.Errorf("failed to inspect container: %v", err)
+	}
+	if resp.ContainerJSONBase == nil || resp.State == nil {
+		return State{}, nil
+	}
+	return State{
+		Running:   resp.State.Running,
+		OOMKilled: resp.State.OOMKilled,
+		ExitCode:  resp.State.ExitCode,
+	}, nil
+}
+
+// ImageDigest reports the registry digest of a pulled image, or its local id
+// for images that were built locally and never pushed. For a reference pinned
+// with @digest it fails unless exactly that build is present.
+func (d *DockerRuntime) ImageDigest(ctx context.Context, image string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, d.requestTimeout)
+	defer cancel()
+	resp, err := d.cli.ImageInspect(ctx, image)
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect image %s: %v", image, err)
+	}
+	if _, pinned, ok := strings.Cut(image, "@"); ok {
+		for _, repoDigest := range resp.RepoDigests {
+			if strings.HasSuffix(repoDigest, "@"+pinned) {
+				return pinned, nil
+			}
+		}
+		return "", fmt.Errorf("image %s does not match its pinned digest", image)
+	}
+	for _, repoDigest := range resp.RepoDigests {
+		if _, digest, ok := strings.Cut(repoDigest, "@"); ok {
+			return digest, nil
+		}
+	}
+	return resp.ID, nil
+}
+
+// PullImage pulls image from its registry. Pulls are not bound by the request
+// timeout, since large images can take minutes; ctx bounds them instead.
+func (d *DockerRuntime) PullImage(ctx context.Context, image string, progress func(message string)) error {
+	stream, err := d.cli.ImagePull(ctx, image, dockerimage.PullOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to pull image %s: %v", image, err)
+	}
+	defer stream.Close()
+	if err := followProgress(stream, progress); err != nil {
+		return fmt.Errorf("failed to pull image %s: %v", image, err)
+	}
+	return nil
+}
+
+// BuildImage builds the Dockerfile in dir, with dir as the build context, and tags the result.
+func (d *DockerRuntime) BuildImage(ctx context.Context, dir string, tag string, buildArgs map[string]string, progress func(message string)) error {
+	files := make(map[string][]byte)
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)], err = os.ReadFile(path)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read build context %s: %v", dir, err)
+	}
+	buildContext, err := createTarArchiveFromMemory(files, 0644)
+	if err != nil {
+		return err
+	}
+
+	args := make(map[string]*string, len(buildArgs))
+	for name, value := range buildArgs {
+		args[name] = &value
+	}
+	resp, err := d.cli.ImageBuild(ctx, buildContext, build.ImageBuildOptions{
+		Tags:        []string{tag},
+		BuildArgs:   args,
+		Remove:      true,
+		ForceRemove: true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to build image %s: %v", tag, err)
+	}
+	defer resp.Body.Close()
+	if err := followProgress(resp.Body, progress); err != nil {
+		return fmt.Errorf("failed to build image %s: %v", tag, err)
+	}
+	return nil
+}
+
+// followProgress reads the JSON message stream of a pull or build until it
+// ends, reporting progress and returning the first error the daemon sends.
+func followProgress(stream io.Reader, progress func(message string)) error {
+	decoder := json.NewDecoder(stream)
+	for {
+		var message struct {
+			Status string `json:"status"`
+			ID     string `json:"id"`
+			Stream string `json:"stream"`
+			Error  string `json:"error"`
+		}
+		if err := decoder.Decode(&message); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to read progress: %v", err)
+		}
+		if message.Error != "" {
+			return errors.New(message.Error)
+		}
+		// Byte-level download and extraction updates are too chatty to report.
+		if progress == nil || message.Status == "Downloading" || message.Status == "Extracting" {
+			continue
+		}
+		switch {
+		case strings.TrimSpace(message.Stream) != "":
+			progress(strings.TrimSpace(message.Stream))
+		case message.Status != "" && message.ID != "":
+			progress(message.ID + ": " + message.Status)
+		case message.Status != "":
+			progress(message.Status)
+		}
+	}
+}
+
+func (d *DockerRuntime) Remove(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, d.requestTimeout)
+	defer cancel()
+	_ = d.cli.ContainerStop(ctx, id, container.StopOptions{})
+	return d.cli.ContainerRemove(ctx, id, container.RemoveOptions{Force: true})
+}
+
+func (d *DockerRuntime) Close() error {
+	return d.cli.Close()
+}
+
+func createTarArchiveFromMemory(files map[string][]byte, mode fs.FileMode) (io.Reader, error) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		content := files[name]
+		header := &tar.Header{
+			Name: name,
+			Mode: int64(mode.Perm()),
+			Size: int64(len(content)),
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return nil, fmt.Errorf("failed to write tar header: %v", err)
+		}
+		if _, err := tw.Write(content); err != nil {
+			return nil, fmt.Errorf("failed to write file content to tar: %v", err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close tar writer: %v", err)
+	}
+	return &buf, nil
+}
+
+func demultiplexDockerOutput(data []byte) (stdout, stderr string) {
+	var stdoutBuf, stderrBuf bytes.Buffer
+
+	for len(data) > 8 {
+		// Docker uses 8-byte headers: [stream_type][0][0][0][size_bytes]
+		streamType := data[0]
+		size := binary.BigEndian.Uint32(data[4:8])
+
+		if len(data) < 8+int(size) {
+			break
+		}
+
+		payload := data[8 : 8+size]
+
+		switch streamType {
+		case 1: // stdout
+			stdoutBuf.Write(payload)
+		case 2: // stderr
+			stderrBuf.Write(payload)
+		}
+
+		data = data[8+size:]
+	}
+
+	return stdoutBuf.String(), stderrBuf.String()
+}
+
+// ctxReader wraps an io.Reader to make it respect a context's deadline.
+func ctxReader(ctx context.Context, r io.Reader) io.Reader {
+	return &cancellableReader{ctx: ctx, r: r}
+}
+
+type cancellableReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (cr *cancellableReader) Read(p []byte) (int, error) {
+	// Check if context is already done.
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	// Perform the read in a goroutine so we can select on the context.
+	type result struct {
+		n   int
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		n, err := cr.r.Read(p)
+		done <- result{n, err}
+	}()
+
+	select {
+	case res := <-done:
+		return res.n, res.err
+	case <-cr.ctx.Done():
+		return 0, cr.ctx.Err()
+	}
+}
@@ -0,0 +1,67 @@
+package sandbox
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/docker/docker/client"
+)
+
+// DockerSettings describes how the pool reaches the Docker daemon.
+// Empty fields fall back to the standard DOCKER_* environment variables.
+type DockerSettings struct {
+	Host       string
+	TLSCACert  string
+	TLSCert    string
+	TLSKey     string
+	APIVersion string
+	// RequestTimeout bounds container lifecycle calls (create, start, remove).
+	// Exec calls are bounded by the judging time limits instead.
+	RequestTimeout time.Duration
+}
+
+const defaultDockerRequestTimeout = 30 * time.Second
+
+// DockerSettingsFromEnv reads the pool's Docker settings from JUDGE_DOCKER_* variables.
+func DockerSettingsFromEnv() (DockerSettings, error) {
+	settings := DockerSettings{
+		Host:           os.Getenv("JUDGE_DOCKER_HOST"),
+		TLSCACert:      os.Getenv("JUDGE_DOCKER_TLS_CA"),
+		TLSCert:        os.Getenv("JUDGE_DOCKER_TLS_CERT"),
+		TLSKey:         os.Getenv("JUDGE_DOCKER_TLS_KEY"),
+		APIVersion:     os.Getenv("JUDGE_DOCKER_API_VERSION"),
+		RequestTimeout: defaultDockerRequestTimeout,
+	}
+	if timeout := os.Getenv("JUDGE_DOCKER_TIMEOUT"); timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil {
+			return settings, fmt.Errorf("invalid JUDGE_DOCKER_TIMEOUT %q: %w", timeout, err)
+		}
+		settings.RequestTimeout = d
+	}
+	return settings, nil
+}
+
+// NewDockerClient builds a Docker client from explicit settings. The API version is
+// negotiated with the daemon unless it is pinned in the settings.
+func NewDockerClient(settings DockerSettings) (*client.Client, error) {
+	opts := []client.Opt{client.FromEnv}
+	if settings.Host != "" {
+		opts = append(opts, client.WithHost(settings.Host))
+	}
+	if settings.TLSCACert != "" || settings.TLSCert != "" || settings.TLSKey != "" {
+		opts = append(opts, client.WithTLSClientConfig(settings.TLSCACert, settings.TLSCert, settings.TLSKey))
+	}
+	if settings.APIVersion != "" {
+		opts = append(opts, client.WithVersion(settings.APIVersion))
+	} else {
+		opts = append(opts, client.WithAPIVersionNegotiation())
+	}
+
+	cli, err := client.NewClientWithOpts(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Docker client: %v", err)
+	}
+	return cli, nil
+}
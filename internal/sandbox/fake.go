@@ -0,0 +1,191 @@
+package sandbox
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FakeRuntime is a Runtime for tests that needs no Docker daemon: every sandbox
+// is a temporary directory and commands run as local processes inside it.
+// It ignores images and applies no isolation or resource limits, so it must
+// never be used to judge real submissions.
+type FakeRuntime struct {
+	mu        sync.Mutex
+	nextID    int
+	sandboxes map[string]*fakeSandbox
+}
+
+type fakeSandbox struct {
+	dir  string
+	spec Spec
+}
+
+func NewFakeRuntime() *FakeRuntime {
+	return &FakeRuntime{sandboxes: make(map[string]*fakeSandbox)}
+}
+
+func (f *FakeRuntime) Create(_ context.Context, spec Spec) (string, error) {
+	dir, err := os.MkdirTemp("", "fake-sandbox-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create sandbox directory: %v", err)
+	}
+
+	box := &fakeSandbox{dir: dir, spec: spec}
+	if err := os.MkdirAll(box.path(spec.WorkingDir), 0755); err != nil {
+		_ = os.RemoveAll(dir)
+		return "", fmt.Errorf("failed to create working directory: %v", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.nextID++
+	id := "fake-" + strconv.Itoa(f.nextID)
+	f.sandboxes[id] = box
+	return id, nil
+}
+
+func (f *FakeRuntime) Exec(ctx context.Context, id string, req ExecRequest) (ExecResult, error) {
+	box, err := f.lookup(id)
+	if err != nil {
+		return ExecResult{}, err
+	}
+	if len(req.Cmd) == 0 {
+		return ExecResult{}, fmt.Errorf("empty command")
+	}
+
+	workingDir := req.WorkingDir
+	if workingDir == "" {
+		workingDir = box.spec.WorkingDir
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, req.Cmd[0], req.Cmd[1:]...)
+	cmd.Dir = box.path(workingDir)
+	cmd.Env = append(os.Environ(), req.Env...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = time.Second
+	if req.Stdin != nil {
+		cmd.Stdin = bytes.NewReader(req.Stdin)
+	}
+
+	err = cmd.Run()
+	if ctx.Err() != nil {
+		return ExecResult{}, ctx.Err()
+	}
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		// Mirror Docker, where a missing binary is reported through the exit code.
+		return ExecResult{Stderr: err.Error(), ExitCode: 127}, nil
+	}
+	return ExecResult{Stdout: stdout.String(), Stderr: stderr.String(), ExitCode: cmd.ProcessState.ExitCode()}, nil
+}
+
+func (f *FakeRuntime) CopyFiles(_ context.Context, id string, dir string, files map[string][]byte, mode fs.FileMode) error {
+	box, err := f.lookup(id)
+	if err != nil {
+		return err
+	}
+	for name, content := range files {
+		if _, err := writeHostFile(box.dir, box.relPath(filepath.Join(dir, name)), content, mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *FakeRuntime) ReadFile(_ context.Context, id string, path string) ([]byte, error) {
+	box, err := f.lookup(id)
+	if err != nil {
+		return nil, err
+	}
+	return readHostFile(box.dir, box.relPath(path))
+}
+
+func (f *FakeRuntime) ListFiles(_ context.Context, id string, dir string) ([]string, error) {
+	box, err := f.lookup(id)
+	if err != nil {
+		return nil, err
+	}
+	return listHostFiles(box.dir, box.relPath(dir))
+}
+
+// SetMemoryLimit only records the limit; the fake enforces no limits.
+func (f *FakeRuntime) SetMemoryLimit(_ context.Context, id string, memoryLimitInMB int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	box, ok := f.sandboxes[id]
+	if !ok {
+		return fmt.Errorf("no such sandbox: %s", id)
+	}
+	box.spec.MemoryLimitInMB = memoryLimitInMB
+	return nil
+}
+
+func (f *FakeRuntime) Inspect(_ context.Context, id string) (State, error) {
+	if _, err := f.lookup(id); err != nil {
+		return State{}, err
+	}
+	return State{Running: true}, nil
+}
+
+func (f *FakeRuntime) Remove(_ context.Context, id string) error {
+	f.mu.Lock()
+	box, ok := f.sandboxes[id]
+	delete(f.sandboxes, id)
+	f.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("no such sandbox: %s", id)
+	}
+	return os.RemoveAll(box.dir)
+}
+
+func (f *FakeRuntime) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for id, box := range f.sandboxes {
+		_ = os.RemoveAll(box.dir)
+		delete(f.sandboxes, id)
+	}
+	return nil
+}
+
+// Len reports how many sandboxes currently exist.
+func (f *FakeRuntime) Len() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.sandboxes)
+}
+
+func (f *FakeRuntime) lookup(id string) (*fakeSandbox, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	box, ok := f.sandboxes[id]
+	if !ok {
+		return nil, fmt.Errorf("no such sandbox: %s", id)
+	}
+	return box, nil
+}
+
+// path maps an absolute path inside the sandbox onto the host directory.
+func (b *fakeSandbox) path(p string) string {
+	return filepath.Join(b.dir, b.relPath(p))
+}
+
+// relPath is p relative to the sandbox directory.
+func (b *fakeSandbox) relPath(p string) string {
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(b.spec.WorkingDir, p)
+	}
+	return strings.TrimPrefix(filepath.Clean("/"+p), "/")
+}
@@ -0,0 +1,118 @@
+package sandbox
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// hostPathBeneath is the host path of name, a path relative to root, for
+// backends whose sandbox files live in a host directory. The sandbox user may
+// control everything under root, so no component of name may be a link: one
+// planted there would have the judge, running as root, write or read outside
+// the sandbox. With create, missing directories are created.
+func hostPathBeneath(root string, name string, create bool) (string, error) {
+	clean := filepath.Clean(name)
+	if filepath.IsAbs(clean) || clean == "." || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("%s is outside the sandbox", name)
+	}
+	parts := strings.Split(clean, string(filepath.Separator))
+	current := root
+	for _, part := range parts[:len(parts)-1] {
+		current = filepath.Join(current, part)
+		info, err := os.Lstat(current)
+		if create && errors.Is(err, fs.ErrNotExist) {
+			if err := os.Mkdir(current, 0755); err != nil {
+				return "", fmt.Errorf("failed to create directory for %s: %v", name, err)
+			}
+			continue
+		} else if err != nil {
+			return "", err
+		}
+		if !info.IsDir() {
+			return "", fmt.Errorf("%s is not a directory in the sandbox", filepath.Dir(name))
+		}
+	}
+	return filepath.Join(current, parts[len(parts)-1]), nil
+}
+
+// writeHostFile writes the sandbox file name, relative to root, and returns
+// its host path.
+func writeHostFile(root string, name string, content []byte, mode fs.FileMode) (string, error) {
+	target, err := hostPathBeneath(root, name, true)
+	if err != nil {
+		return "", err
+	}
+	// A read-only file or a link left by an earlier submission is replaced, not
+	// written to or through.
+	if err := os.Remove(target); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return "", fmt.Errorf("failed to replace %s: %v", name, err)
+	}
+	file, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode)
+	if err != nil {
+		return "", fmt.Errorf("failed to write %s: %v", name, err)
+	}
+	_, err = file.Write(content)
+	// OpenFile applies the umask.
+	if chmodErr := file.Chmod(mode); err == nil {
+		err = chmodErr
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to write %s: %v", name, err)
+	}
+	return target, nil
+}
+
+// readHostFile reads the sandbox file name, relative to root, which must be a
+// regular file.
+func readHostFile(root string, name string) ([]byte, error) {
+	target, err := hostPathBeneath(root, name, false)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Lstat(target)
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("%s is not a regular file", name)
+	}
+	return os.ReadFile(target)
+}
+
+// listHostFiles lists the regular files below the sandbox directory name,
+// relative to root, or below root itself when name is ".". Links are not
+// followed.
+func listHostFiles(root string, name string) ([]string, error) {
+	dir := root
+	if filepath.Clean(name) != "." {
+		target, err := hostPathBeneath(root, name, false)
+		if err != nil {
+			return nil, err
+		}
+		info, err := os.Lstat(target)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("%s is not a directory in the sandbox", name)
+		}
+		dir = target
+	}
+	var names []string
+	err := filepath.WalkDir(dir, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil || !entry.Type().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		names = append(names, filepath.ToSlash(rel))
+		return err
+	})
+	return names, err
+}
@@ -0,0 +1,83 @@
+package sandbox
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// NativeSettings configures the Docker-free Linux backend. The judge must run
+// as root for it, with cgroup v2 delegated at CgroupParent.
+type NativeSettings struct {
+	// Root is where sandbox workspaces and mount points are created.
+	Root string
+	// CgroupParent is the cgroup v2 directory each sandbox gets a child of.
+	CgroupParent string
+	// Rootfs maps image names to prepared root filesystems on the host (for
+	// example an exported image). Images without an entry use the host root, read-only.
+	Rootfs map[string]string
+	// UID and GID are the unprivileged ids untrusted commands run as.
+	UID int
+	GID int
+}
+
+// NativeSettingsFromEnv reads the native backend settings from JUDGE_NATIVE_* variables.
+func NativeSettingsFromEnv() (NativeSettings, error) {
+	settings := NativeSettings{
+		Root:         os.Getenv("JUDGE_NATIVE_ROOT"),
+		CgroupParent: os.Getenv("JUDGE_NATIVE_CGROUP"),
+		Rootfs:       map[string]string{},
+		UID:          65534,
+		GID:          65534,
+	}
+	if settings.Root == "" {
+		settings.Root = "/var/lib/judge/sandboxes"
+	}
+	if settings.CgroupParent == "" {
+		settings.CgroupParent = "/sys/fs/cgroup/judge"
+	}
+
+	// JUDGE_NATIVE_ROOTFS is a comma separated list of image=path pairs.
+	for _, pair := range strings.Split(os.Getenv("JUDGE_NATIVE_ROOTFS"), ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		image, path, ok := strings.Cut(pair, "=")
+		if !ok {
+			return settings, fmt.Errorf("invalid JUDGE_NATIVE_ROOTFS entry %q, want image=path", pair)
+		}
+		settings.Rootfs[strings.TrimSpace(image)] = strings.TrimSpace(path)
+	}
+
+	for name, target := range map[string]*int{"JUDGE_NATIVE_UID": &settings.UID, "JUDGE_NATIVE_GID": &settings.GID} {
+		if value := os.Getenv(name); value != "" {
+			id, err := strconv.Atoi(value)
+			if err != nil {
+				return settings, fmt.Errorf("invalid %s %q: %w", name, value, err)
+			}
+			*target = id
+		}
+	}
+	return settings, nil
+}
+
+// NewRuntime builds the backend named by kind: "docker" (the default) or "native".
+func NewRuntime(kind string) (Runtime, error) {
+	switch kind {
+	case "", "docker":
+		settings, err := DockerSettingsFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		return NewDockerRuntime(settings)
+	case "native":
+		settings, err := NativeSettingsFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		return NewNativeRuntime(settings)
+	default:
+		return nil, fmt.Errorf("unknown sandbox backend %q", kind)
+	}
+}
@@ -0,0 +1,224 @@
+//go:build amd64 || arm64
+
+package sandbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"golang.org/x/sys/unix"
+)
+
+const initEnv = "JUDGE_SANDBOX_INIT"
+
+// initConfig is handed from NativeRuntime.Exec to the sandbox init process.
+type initConfig struct {
+	Rootfs     string   `json:"rootfs"`
+	MountDir   string   `json:"mountDir"`
+	Workspace  string   `json:"workspace"`
+	WorkingDir string   `json:"workingDir"`
+	Chdir      string   `json:"chdir"`
+	Cmd        []string `json:"cmd"`
+	Env        []string `json:"env"`
+	UID        int      `json:"uid"`
+	GID        int      `json:"gid"`
+	CPUSeconds uint64   `json:"cpuSeconds"`
+	MemoryMB   int      `json:"memoryMB"`
+}
+
+// rootfsDirs are the parts of the image exposed, read-only, inside the sandbox.
+var rootfsDirs = []string{"bin", "sbin", "lib", "lib32", "lib64", "libx32", "usr", "etc", "opt"}
+
+// RunInitIfRequested turns the current process into the native sandbox init
+// when it was started by NativeRuntime.Exec, and never returns in that case.
+// Binaries that may use the native backend call it first thing in main.
+func RunInitIfRequested() {
+	encoded, ok := os.LookupEnv(initEnv)
+	if !ok {
+		return
+	}
+	var cfg initConfig
+	if err := json.Unmarshal([]byte(encoded), &cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "sandbox init: invalid config: %v\n", err)
+		os.Exit(127)
+	}
+	// Credentials, seccomp and exec must all apply to the same thread.
+	runtime.LockOSThread()
+	if err := runInit(cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "sandbox init: %v\n", err)
+		os.Exit(127)
+	}
+}
+
+func runInit(cfg initConfig) error {
+	if err := buildRoot(cfg); err != nil {
+		return err
+	}
+	if err := unix.Chroot(cfg.MountDir); err != nil {
+		return fmt.Errorf("chroot: %v", err)
+	}
+	if err := unix.Chdir(cfg.Chdir); err != nil {
+		return fmt.Errorf("chdir %s: %v", cfg.Chdir, err)
+	}
+	if err := applyRlimits(cfg); err != nil {
+		return err
+	}
+
+	if err := unix.Setgroups(nil); err != nil {
+		return fmt.Errorf("setgroups: %v", err)
+	}
+	if err := unix.Setresgid(cfg.GID, cfg.GID, cfg.GID); err != nil {
+		return fmt.Errorf("setgid: %v", err)
+	}
+	if err := unix.Setresuid(cfg.UID, cfg.UID, cfg.UID); err != nil {
+		return fmt.Errorf("setuid: %v", err)
+	}
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return fmt.Errorf("no_new_privs: %v", err)
+	}
+	if err := installSeccompFilter(); err != nil {
+		return err
+	}
+
+	path := cfg.Cmd[0]
+	if filepath.Base(path) == path {
+		resolved, err := lookPath(path, cfg.Env)
+		if err != nil {
+			return err
+		}
+		path = resolved
+	}
+	return unix.Exec(path, cfg.Cmd, cfg.Env)
+}
+
+// buildRoot assembles the sandbox filesystem at cfg.MountDir: a tmpfs holding
+// read-only binds of the image, the writable workspace, /proc, /tmp and a minimal /dev.
+func buildRoot(cfg initConfig) error {
+	if err := unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("make mounts private: %v", err)
+	}
+	root := cfg.MountDir
+	if err := unix.Mount("tmpfs", root, "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "size=16m,mode=755"); err != nil {
+		return fmt.Errorf("mount root tmpfs: %v", err)
+	}
+
+	for _, dir := range rootfsDirs {
+		source := filepath.Join(cfg.Rootfs, dir)
+		info, err := os.Lstat(source)
+		if err != nil {
+			continue
+		}
+		target := filepath.Join(root, dir)
+		if info.Mode()&os.ModeSymlink != 0 {
+			link, err := os.Readlink(source)
+			if err != nil {
+				return fmt.Errorf("readlink %s: %v", source, err)
+			}
+			if err := os.Symlink(link, target); err != nil {
+				return fmt.Errorf("symlink %s: %v", target, err)
+			}
+			continue
+		}
+		if err := bindMount(source, target, true, true); err != nil {
+			return err
+		}
+	}
+
+	if err := bindMount(cfg.Workspace, filepath.Join(root, cfg.WorkingDir), true, false); err != nil {
+		return err
+	}
+	if err := mkdirMount("proc", filepath.Join(root, "proc"), "proc", unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOEXEC, ""); err != nil {
+		return err
+	}
+	if err := mkdirMount("tmpfs", filepath.Join(root, "tmp"), "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "size=64m,mode=1777"); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Join(root, "dev"), 0755); err != nil {
+		return fmt.Errorf("mkdir /dev: %v", err)
+	}
+	for _, device := range []string{"null", "zero", "random", "urandom"} {
+		if err := bindMount(filepath.Join("/dev", device), filepath.Join(root, "dev", device), false, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func mkdirMount(source, target, fstype string, flags uintptr, data string) error {
+	if err := os.MkdirAll(target, 0755); err != nil {
+		return fmt.Errorf("mkdir %s: %v", target, err)
+	}
+	if err := unix.Mount(source, target, fstype, flags, data); err != nil {
+		return fmt.Errorf("mount %s: %v", target, err)
+	}
+	return nil
+}
+
+func bindMount(source, target string, isDir bool, readOnly bool) error {
+	if isDir {
+		if err := os.MkdirAll(target, 0755); err != nil {
+			return fmt.Errorf("mkdir %s: %v", target, err)
+		}
+	} else if err := os.WriteFile(target, nil, 0644); err != nil {
+		return fmt.Errorf("create %s: %v", target, err)
+	}
+	if err := unix.Mount(source, target, "", unix.MS_BIND|unix.MS_REC, ""); err != nil {
+		return fmt.Errorf("bind %s: %v", source, err)
+	}
+	flags := uintptr(unix.MS_BIND | unix.MS_REMOUNT | unix.MS_NOSUID)
+	if readOnly {
+		flags |= unix.MS_RDONLY
+	}
+	if err := unix.Mount("", target, "", flags, ""); err != nil {
+		return fmt.Errorf("remount %s: %v", target, err)
+	}
+	return nil
+}
+
+func applyRlimits(cfg initConfig) error {
+	limits := map[int]uint64{
+		unix.RLIMIT_CORE:   0,
+		unix.RLIMIT_NOFILE: 64,
+		unix.RLIMIT_FSIZE:  64 << 20,
+	}
+	if cfg.CPUSeconds > 0 {
+		limits[unix.RLIMIT_CPU] = cfg.CPUSeconds
+	}
+	if cfg.MemoryMB > 0 {
+		// Contest code often recurses deeply; the cgroup still caps total memory.
+		limits[unix.RLIMIT_STACK] = uint64(cfg.MemoryMB) << 20
+	}
+	for resource, value := range limits {
+		if err := unix.Setrlimit(resource, &unix.Rlimit{Cur: value, Max: value}); err != nil {
+			return fmt.Errorf("setrlimit %d: %v", resource, err)
+		}
+	}
+	return nil
+}
+
+// lookPath resolves name against the PATH in env, inside the new root.
+func lookPath(name string, env []string) (string, error) {
+	path := ""
+	for _, kv := range env {
+		if value, ok := cutEnv(kv, "PATH"); ok {
+			path = value
+		}
+	}
+	for _, dir := range filepath.SplitList(path) {
+		candidate := filepath.Join(dir, name)
+		if info, err := os.Stat(candidate); err == nil && info.Mode().IsRegular() && info.Mode()&0111 != 0 {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("%s: command not found", name)
+}
+
+func cutEnv(kv, key string) (string, bool) {
+	if len(kv) > len(key) && kv[:len(key)] == key && kv[len(key)] == '=' {
+		return kv[len(key)+1:], true
+	}
+	return "", false
+}
@@ -0,0 +1,387 @@
+//go:build amd64 || arm64
+
+package sandbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const cpuPeriodMicros = 100000
+
+// NativeRuntime isolates commands with Linux namespaces, a cgroup v2 per
+// sandbox, rlimits and a seccomp filter, without a Docker daemon. Every Exec
+// re-executes the judge binary as a small init (see RunInitIfRequested) that
+// builds the sandbox filesystem, drops privileges and execs the command.
+type NativeRuntime struct {
+	settings  NativeSettings
+	mu        sync.Mutex
+	nextID    int
+	sandboxes map[string]*nativeSandbox
+}
+
+type nativeSandbox struct {
+	dir    string
+	cgroup string
+	rootfs string
+	spec   Spec
+}
+
+func (b *nativeSandbox) workspace() string { return filepath.Join(b.dir, "workspace") }
+func (b *nativeSandbox) mountDir() string  { return filepath.Join(b.dir, "root") }
+
+// workspacePath maps a path inside the sandbox to one relative to the
+// workspace. Only the working directory is writable and shared with the host.
+func (b *nativeSandbox) workspacePath(p string) (string, error) {
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(b.spec.WorkingDir, p)
+	}
+	rel, err := filepath.Rel(b.spec.WorkingDir, filepath.Clean(p))
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", fmt.Errorf("%s is outside the sandbox working directory", p)
+	}
+	return rel, nil
+}
+
+func NewNativeRuntime(settings NativeSettings) (Runtime, error) {
+	if os.Geteuid() != 0 {
+		return nil, fmt.Errorf("the native sandbox backend must run as root")
+	}
+	if _, err := os.Stat(filepath.Join(settings.CgroupParent, "cgroup.controllers")); err != nil {
+		return nil, fmt.Errorf("cgroup v2 parent %s is not usable: %v", settings.CgroupParent, err)
+	}
+	// Enable the controllers sandboxes are limited with in their parent.
+	if err := os.WriteFile(filepath.Join(settings.CgroupParent, "cgroup.subtree_control"), []byte("+memory +cpu +cpuset +pids"), 0644); err != nil {
+		return nil, fmt.Errorf("failed to enable cgroup controllers: %v", err)
+	}
+	if err := os.MkdirAll(settings.Root, 0711); err != nil {
+		return nil, fmt.Errorf("failed to create sandbox root: %v", err)
+	}
+	return &NativeRuntime{settings: settings, sandboxes: make(map[string]*nativeSandbox)}, nil
+}
+
+func (n *NativeRuntime) Create(_ context.Context, spec Spec) (string, error) {
+	if spec.WorkingDir == "" {
+		spec.WorkingDir = "/workspace"
+	}
+	rootfs := "/"
+	// Rootfs entries may name an image with or without its pinned digest.
+	image, _, _ := strings.Cut(spec.Image, "@")
+	if path, ok := n.settings.Rootfs[spec.Image]; ok {
+		rootfs = path
+	} else if path, ok := n.settings.Rootfs[image]; ok {
+		rootfs = path
+	}
+
+	n.mu.Lock()
+	n.nextID++
+	id := fmt.Sprintf("native-%d-%d", os.Getpid(), n.nextID)
+	n.mu.Unlock()
+
+	box := &nativeSandbox{
+		dir:    filepath.Join(n.settings.Root, id),
+		cgroup: filepath.Join(n.settings.CgroupParent, id),
+		rootfs: rootfs,
+		spec:   spec,
+	}
+	if err := n.setup(box); err != nil {
+		n.teardown(box)
+		return "", err
+	}
+
+	n.mu.Lock()
+	n.sandboxes[id] = box
+	n.mu.Unlock()
+	return id, nil
+}
+
+func (n *NativeRuntime) setup(box *nativeSandbox) error {
+	for _, dir := range []string{box.workspace(), box.mountDir()} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create %s: %v", dir, err)
+		}
+	}
+	if err := os.Chown(box.workspace(), n.settings.UID, n.settings.GID); err != nil {
+		return fmt.Errorf("failed to hand the workspace to the sandbox user: %v", err)
+	}
+
+	if err := os.Mkdir(box.cgroup, 0755); err != nil {
+		return fmt.Errorf("failed to create cgroup: %v", err)
+	}
+	limits := map[string]string{
+		"pids.max": "64",
+	}
+	if box.spec.MemoryLimitInMB > 0 {
+		limits["memory.max"] = strconv.FormatInt(int64(box.spec.MemoryLimitInMB)*1024*1024, 10)
+		limits["memory.swap.max"] = "0"
+	}
+	if box.spec.NanoCPUs > 0 {
+		quota := box.spec.NanoCPUs * cpuPeriodMicros / 1e9
+		limits["cpu.max"] = fmt.Sprintf("%d %d", quota, cpuPeriodMicros)
+	}
+	if box.spec.CpusetCpus != "" {
+		limits["cpuset.cpus"] = box.spec.CpusetCpus
+	}
+	for file, value := range limits {
+		if err := os.WriteFile(filepath.Join(box.cgroup, file), []byte(value), 0644); err != nil {
+			return fmt.Errorf("failed to set %s: %v", file, err)
+		}
+	}
+	return nil
+}
+
+func (n *NativeRuntime) Exec(ctx context.Context, id string, req ExecRequest) (ExecResult, error) {
+	box, err := n.lookup(id)
+	if err != nil {
+		return ExecResult{}, err
+	}
+	if len(req.Cmd) == 0 {
+		return ExecResult{}, fmt.Errorf("empty command")
+	}
+
+	cfg := initConfig{
+		Rootfs:     box.rootfs,
+		MountDir:   box.mountDir(),
+		Workspace:  box.workspace(),
+		WorkingDir: box.spec.WorkingDir,
+		Chdir:      req.WorkingDir,
+		Cmd:        req.Cmd,
+		Env:        append([]string{"PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin", "HOME=/tmp"}, req.Env...),
+		UID:        n.settings.UID,
+		GID:        n.settings.GID,
+		CPUSeconds: cpuSecondsFor(ctx),
+		MemoryMB:   box.spec.MemoryLimitInMB,
+	}
+	if cfg.Chdir == "" {
+		cfg.Chdir = box.spec.WorkingDir
+	}
+	encoded, err := json.Marshal(cfg)
+	if err != nil {
+		return ExecResult{}, fmt.Errorf("failed to encode sandbox init config: %v", err)
+	}
+
+	cgroupFD, err := unix.Open(box.cgroup, unix.O_DIRECTORY|unix.O_RDONLY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return ExecResult{}, fmt.Errorf("failed to open cgroup: %v", err)
+	}
+	defer unix.Close(cgroupFD)
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "/proc/self/exe")
+	cmd.Args = []string{"judge-sandbox-init"}
+	cmd.Env = []string{initEnv + "=" + string(encoded)}
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = time.Second
+	if req.Stdin != nil {
+		cmd.Stdin = bytes.NewReader(req.Stdin)
+	}
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags: syscall.CLONE_NEWNS | syscall.CLONE_NEWPID | syscall.CLONE_NEWNET |
+			syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS | unix.CLONE_NEWCGROUP,
+		UseCgroupFD: true,
+		CgroupFD:    cgroupFD,
+		Pdeathsig:   syscall.SIGKILL,
+	}
+
+	// The command is pid 1 of its own pid namespace, so the kernel kills
+	// anything it left behind as soon as it exits or is killed.
+	err = cmd.Run()
+	if ctx.Err() != nil {
+		return ExecResult{}, ctx.Err()
+	}
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return ExecResult{}, fmt.Errorf("failed to start sandboxed command: %v", err)
+	}
+	return ExecResult{Stdout: stdout.String(), Stderr: stderr.String(), ExitCode: exitCode(cmd.ProcessState)}, nil
+}
+
+// exitCode reports signal deaths the way Docker does, as 128 + signal number.
+func exitCode(state *os.ProcessState) int {
+	if status, ok := state.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return 128 + int(status.Signal())
+	}
+	return state.ExitCode()
+}
+
+// cpuSecondsFor turns the context deadline into an RLIMIT_CPU backstop.
+func cpuSecondsFor(ctx context.Context) uint64 {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return 0
+	}
+	return uint64(math.Ceil(time.Until(deadline).Seconds())) + 1
+}
+
+func (n *NativeRuntime) CopyFiles(_ context.Context, id string, dir string, files map[string][]byte, mode fs.FileMode) error {
+	box, err := n.lookup(id)
+	if err != nil {
+		return err
+	}
+	for name, content := range files {
+		rel, err := box.workspacePath(filepath.Join(dir, name))
+		if err != nil {
+			return err
+		}
+		target, err := writeHostFile(box.workspace(), rel, content, mode)
+		if err != nil {
+			return err
+		}
+		// Read-only files, such as a grader's, stay root's so that the sandbox
+		// user cannot make them writable again.
+		if mode&0200 == 0 {
+			continue
+		}
+		if err := os.Lchown(target, n.settings.UID, n.settings.GID); err != nil {
+			return fmt.Errorf("failed to hand %s to the sandbox user: %v", name, err)
+		}
+	}
+	return nil
+}
+
+func (n *NativeRuntime) ReadFile(_ context.Context, id string, path string) ([]byte, error) {
+	box, err := n.lookup(id)
+	if err != nil {
+		return nil, err
+	}
+	rel, err := box.workspacePath(path)
+	if err != nil {
+		return nil, err
+	}
+	return readHostFile(box.workspace(), rel)
+}
+
+func (n *NativeRuntime) ListFiles(_ context.Context, id string, dir string) ([]string, error) {
+	box, err := n.lookup(id)
+	if err != nil {
+		return nil, err
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(box.spec.WorkingDir, dir)
+	}
+	rel := "."
+	if filepath.Clean(dir) != filepath.Clean(box.spec.WorkingDir) {
+		if rel, err = box.workspacePath(dir); err != nil {
+			return nil, err
+		}
+	}
+	return listHostFiles(box.workspace(), rel)
+}
+
+func (n *NativeRuntime) SetMemoryLimit(_ context.Context, id string, memoryLimitInMB int) error {
+	box, err := n.lookup(id)
+	if err != nil {
+		return err
+	}
+	limit := strconv.FormatInt(int64(memoryLimitInMB)*1024*1024, 10)
+	if err := os.WriteFile(filepath.Join(box.cgroup, "memory.max"), []byte(limit), 0644); err != nil {
+		return fmt.Errorf("failed to set memory.max: %v", err)
+	}
+	return nil
+}
+
+func (n *NativeRuntime) Inspect(_ context.Context, id string) (State, error) {
+	box, err := n.lookup(id)
+	if err != nil {
+		return State{}, err
+	}
+	state := State{Running: true}
+	events, err := os.ReadFile(filepath.Join(box.cgroup, "memory.events"))
+	if err != nil {
+		return state, nil
+	}
+	for _, line := range strings.Split(string(events), "\n") {
+		if count, ok := strings.CutPrefix(line, "oom_kill "); ok && count != "0" {
+			state.OOMKilled = true
+		}
+	}
+	return state, nil
+}
+
+func (n *NativeRuntime) Remove(_ context.Context, id string) error {
+	n.mu.Lock()
+	box, ok := n.sandboxes[id]
+	delete(n.sandboxes, id)
+	n.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("no such sandbox: %s", id)
+	}
+	return n.teardown(box)
+}
+
+func (n *NativeRuntime) teardown(box *nativeSandbox) error {
+	err := killCgroup(box.cgroup, 5*time.Second)
+	if err == nil {
+		if err = os.Remove(box.cgroup); os.IsNotExist(err) {
+			err = nil
+		}
+	}
+	if rmErr := os.RemoveAll(box.dir); rmErr != nil && err == nil {
+		err = rmErr
+	}
+	return err
+}
+
+// killCgroup kills every process left in the cgroup, forked children
+// included, and waits until the cgroup reports it is empty, as it can only be
+// removed then.
+func killCgroup(cgroup string, timeout time.Duration) error {
+	err := os.WriteFile(filepath.Join(cgroup, "cgroup.kill"), []byte("1"), 0644)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to kill the sandbox processes: %v", err)
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		events, err := os.ReadFile(filepath.Join(cgroup, "cgroup.events"))
+		if err != nil {
+			return fmt.Errorf("failed to read the sandbox cgroup events: %v", err)
+		}
+		if slices.Contains(strings.Split(string(events), "\n"), "populated 0") {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("sandbox processes still running %v after being killed", timeout)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func (n *NativeRuntime) Close() error {
+	n.mu.Lock()
+	boxes := n.sandboxes
+	n.sandboxes = make(map[string]*nativeSandbox)
+	n.mu.Unlock()
+	for _, box := range boxes {
+		_ = n.teardown(box)
+	}
+	return nil
+}
+
+func (n *NativeRuntime) lookup(id string) (*nativeSandbox, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	box, ok := n.sandboxes[id]
+	if !ok {
+		return nil, fmt.Errorf("no such sandbox: %s", id)
+	}
+	return box, nil
+}
@@ -0,0 +1,13 @@
+//go:build !linux || !(amd64 || arm64)
+
+package sandbox
+
+import "fmt"
+
+// NewNativeRuntime is only available on Linux (amd64 and arm64).
+func NewNativeRuntime(settings NativeSettings) (Runtime, error) {
+	return nil, fmt.Errorf("the native sandbox backend requires Linux on amd64 or arm64")
+}
+
+// RunInitIfRequested is a no-op where the native backend is unavailable.
+func RunInitIfRequested() {}
@@ -0,0 +1,76 @@
+package sandbox
+
+import (
+	"context"
+	"io/fs"
+)
+
+// Runtime is a backend able to host sandboxes that untrusted code is compiled
+// and executed in. The container pool and the language runners only talk to a
+// Runtime, so the backend can be swapped (Docker, fake) without touching them.
+type Runtime interface {
+	// Create starts a new sandbox and returns its id.
+	Create(ctx context.Context, spec Spec) (string, error)
+	// Exec runs a command inside the sandbox and waits for it to finish.
+	// It returns the context error when ctx expires before the command does.
+	Exec(ctx context.Context, id string, req ExecRequest) (ExecResult, error)
+	// CopyFiles writes files, keyed by path relative to dir, into the sandbox
+	// with the given permission bits.
+	CopyFiles(ctx context.Context, id string, dir string, files map[string][]byte, mode fs.FileMode) error
+	// ReadFile returns the content of a file inside the sandbox.
+	ReadFile(ctx context.Context, id string, path string) ([]byte, error)
+	// ListFiles returns the regular files below dir inside the sandbox, as
+	// slash-separated paths relative to it, in no particular order.
+	ListFiles(ctx context.Context, id string, dir string) ([]string, error)
+	// SetMemoryLimit changes the memory limit of a running sandbox, e.g. to
+	// tighten it once compilation is done.
+	SetMemoryLimit(ctx context.Context, id string, memoryLimitInMB int) error
+	Inspect(ctx context.Context, id string) (State, error)
+	Remove(ctx context.Context, id string) error
+	// Close releases the backend itself; sandboxes must be removed beforehand.
+	Close() error
+}
+
+// ImageInspector is implemented by runtimes that can tell which exact image
+// build a sandbox would be created from.
+type ImageInspector interface {
+	// ImageDigest returns the content digest of image, e.g. "sha256:…".
+	ImageDigest(ctx context.Context, image string) (string, error)
+}
+
+// ImagePuller is implemented by runtimes that fetch images from a registry.
+type ImagePuller interface {
+	// PullImage fetches image, reporting progress messages as they arrive.
+	PullImage(ctx context.Context, image string, progress func(message string)) error
+}
+
+// Spec describes the sandbox to create.
+type Spec struct {
+	Image           string
+	WorkingDir      string
+	MemoryLimitInMB int
+	// NanoCPUs is the CPU quota in units of 1e-9 CPUs; zero means unlimited.
+	NanoCPUs int64
+	// CpusetCpus pins the sandbox to the listed cores, e.g. "3"; empty means no pinning.
+	CpusetCpus string
+}
+
+type ExecRequest struct {
+	Cmd        []string
+	Env        []string
+	WorkingDir string
+	// Stdin is fed to the command when non-nil.
+	Stdin []byte
+}
+
+type ExecResult struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+}
+
+type State struct {
+	Running   bool
+	OOMKilled bool
+	ExitCode  int
+}
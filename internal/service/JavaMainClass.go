@@ -0,0 +1,40 @@
+package service
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	javaCommentOrLiteral = regexp.MustCompile(`(?s)/\*.*?\*/|//[^\n]*|"""(?:\\.|[^\\])*?"""|"(?:\\.|[^"\\])*"|'(?:\\.|[^'\\])*'`)
+	javaPublicClass      = regexp.MustCompile(`\bpublic\s+(?:(?:final|abstract|static|strictfp)\s+)*(?:class|interface|enum|record)\s+([A-Za-z_$][A-Za-z0-9_$]*)`)
+	javaPackage          = regexp.MustCompile(`\bpackage\s+([A-Za-z_$][A-Za-z0-9_$.\s]*?)\s*;`)
+)
+
+// JavaMainClass returns the name of the first public top-level type in a Java
+// submission, ignoring comments, literals and text blocks as well as public
+// types nested in another. Submissions without a public top-level type are
+// expected to declare a class named Main.
+func JavaMainClass(code string) string {
+	stripped := javaCommentOrLiteral.ReplaceAllString(code, " ")
+	depth, scanned := 0, 0
+	for _, match := range javaPublicClass.FindAllStringSubmatchIndex(stripped, -1) {
+		depth += strings.Count(stripped[scanned:match[0]], "{") - strings.Count(stripped[scanned:match[0]], "}")
+		scanned = match[0]
+		if depth == 0 {
+			return stripped[match[2]:match[3]]
+		}
+	}
+	return "Main"
+}
+
+// JavaPackage returns the package a Java submission declares, or "" for the
+// unnamed package. Packaged classes cannot be run by their simple name, so
+// such submissions are refused.
+func JavaPackage(code string) string {
+	stripped := javaCommentOrLiteral.ReplaceAllString(code, " ")
+	if match := javaPackage.FindStringSubmatch(stripped); match != nil {
+		return strings.Join(strings.Fields(match[1]), "")
+	}
+	return ""
+}
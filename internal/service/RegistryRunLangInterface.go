@@ -0,0 +1,423 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"judging-service/internal/customErrors"
+	"judging-service/internal/models"
+	"maps"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RegistryRunLangInterface runs any language declared in the language registry,
+// driven entirely by its LanguageSpec.
+type RegistryRunLangInterface struct {
+	Spec    models.LanguageSpec
+	Limit   models.ResourceLimit
+	Options models.SubmissionOptions
+}
+
+func (r RegistryRunLangInterface) CopyCodeToFile(containerCpy *models.Container, code string) (string, error) {
+	if r.restrictsModules() {
+		if err := copyImportHook(containerCpy); err != nil {
+			return "", err
+		}
+	}
+	files, err := r.SourceFiles(code)
+	if err != nil {
+		return "", err
+	}
+	var graderFiles map[string]string
+	if r.Options.Grader != nil {
+		graderFiles = r.Options.Grader.Files
+	}
+	if err := CopyCodeToFileGlobalUtil(containerCpy, files, graderFiles); err != nil {
+		return "", err
+	}
+	return r.SourceFileName(code), nil
+}
+
+// Program is the source that is compiled: the code itself, or the problem's
+// driver with the code injected.
+func (r RegistryRunLangInterface) Program(code string) string {
+	if r.Options.Driver == nil {
+		return code
+	}
+	return strings.Replace(r.Options.Driver.Template, models.DriverSolutionMarker, code, 1)
+}
+
+// SourceFiles are the files written to the workspace for the code, keyed by
+// name: the program, or the project's files in its place, the driver's files
+// and the grader's files.
+func (r RegistryRunLangInterface) SourceFiles(code string) (map[string]string, error) {
+	fileName := r.SourceFileName(code)
+	files := map[string]string{fileName: r.Program(code)}
+	if project := r.Options.Project; project != nil {
+		if r.Options.Driver != nil {
+			return nil, fmt.Errorf("drivers do not apply to project submissions")
+		}
+		if _, ok := project.Files[fileName]; !ok {
+			return nil, fmt.Errorf("the project has no entry file %q", fileName)
+		}
+		files = maps.Clone(project.Files)
+	}
+	if driver := r.Options.Driver; driver != nil {
+		if !strings.Contains(driver.Template, models.DriverSolutionMarker) {
+			return nil, fmt.Errorf("driver template has no %s marker", models.DriverSolutionMarker)
+		}
+		for name, content := range driver.Files {
+			if path.Clean(name) == fileName {
+				return nil, fmt.Errorf("driver file %q would replace the program", name)
+			}
+			files[name] = content
+		}
+	}
+	if grader := r.Options.Grader; grader != nil {
+		if err := validateGrader(*grader); err != nil {
+			return nil, err
+		}
+		for name, content := range grader.Files {
+			if _, ok := files[path.Clean(name)]; ok {
+				return nil, fmt.Errorf("grader file %q would replace a file of the submission", name)
+			}
+			files[name] = content
+		}
+	}
+	return files, nil
+}
+
+// validateGrader checks that the files a grader names are among its own.
+func validateGrader(grader models.Grader) error {
+	names := append(append([]string(nil), grader.Sources...), grader.Entry)
+	for _, name := range names {
+		if _, ok := grader.Files[name]; name != "" && !ok {
+			return fmt.Errorf("grader names %q but has no such file", name)
+		}
+	}
+	return nil
+}
+
+// SourceFileName is the name the submission is written to inside the sandbox.
+func (r RegistryRunLangInterface) SourceFileName(code string) string {
+	if project := r.Options.Project; project != nil {
+		if project.Entry != "" {
+			return project.Entry
+		}
+		return r.Spec.SourceFile
+	}
+	if r.Options.Grader != nil && r.Options.Grader.SubmissionFile != "" {
+		return r.Options.Grader.SubmissionFile
+	}
+	if r.Spec.DetectMainClass {
+		// javac requires a public class to live in a file named after it.
+		return JavaMainClass(r.Program(code)) + filepath.Ext(r.Spec.SourceFile)
+	}
+	return r.Spec.SourceFile
+}
+
+// compileCommand is the compile command template: the project's build
+// command when it has one, or the language's.
+func (r RegistryRunLangInterface) compileCommand() []string {
+	if r.Options.Project != nil && r.Options.Project.BuildCommand != nil {
+		return r.Options.Project.BuildCommand
+	}
+	return r.Spec.CompileCommand
+}
+
+// CompileCommandLine is the compile command for fileName with every placeholder
+// expanded, or nil when the language has no compile step. The project's other
+// source files and the grader's sources are compiled along with fileName.
+func (r RegistryRunLangInterface) CompileCommandLine(fileName string) []string {
+	template := r.compileCommand()
+	if len(template) == 0 {
+		return nil
+	}
+	flags := r.Spec.DefaultFlags
+	if diagnostics := r.diagnostics(); diagnostics != nil && diagnostics.Flags != nil {
+		flags = diagnostics.Flags
+	} else if r.Options.CompileFlags != nil {
+		flags = r.Options.CompileFlags
+	}
+	sources := r.projectSources(fileName)
+	if r.Options.Grader != nil {
+		sources = append(sources, r.Options.Grader.Sources...)
+	}
+	args := make([]string, 0, len(template)+len(flags)+len(sources))
+	expanded := r.expand(template, fileName)
+	for i, arg := range template {
+		switch arg {
+		case "{flags}":
+			args = append(args, flags...)
+			if !r.Options.Diagnostics {
+				args = append(args, r.Spec.RunImageFlags...)
+			}
+		case "{source}":
+			args = append(append(args, expanded[i]), sources...)
+		default:
+			args = append(args, expanded[i])
+		}
+	}
+	return args
+}
+
+// projectSources are the project's files other than fileName that share its
+// extension, in order, unless the compiler finds them itself.
+func (r RegistryRunLangInterface) projectSources(fileName string) []string {
+	if r.Options.Project == nil || r.Spec.SingleSource {
+		return nil
+	}
+	var sources []string
+	for name := range r.Options.Project.Files {
+		if name != fileName && filepath.Ext(name) == filepath.Ext(fileName) {
+			sources = append(sources, name)
+		}
+	}
+	sort.Strings(sources)
+	return sources
+}
+
+// RunCommandLine is the command that runs the program compiled from fileName,
+// or the grader's entry in its place.
+func (r RegistryRunLangInterface) RunCommandLine(fileName string) string {
+	runCommand := r.Spec.RunCommand
+	if diagnostics := r.diagnostics(); diagnostics != nil && len(diagnostics.RunCommand) > 0 {
+		runCommand = diagnostics.RunCommand
+	}
+	if r.Options.Grader != nil && r.Options.Grader.Entry != "" {
+		fileName = r.Options.Grader.Entry
+	}
+	return strings.Join(r.expand(runCommand, fileName), " ")
+}
+
+// CompileCode runs the compile command, if the language has one, and returns the run command.
+func (r RegistryRunLangInterface) CompileCode(containerCpy *models.Container, fileName string, ctx context.Context) (string, error) {
+	var executableFileCommand = r.RunCommandLine(fileName)
+	if len(r.compileCommand()) == 0 {
+		return executableFileCommand, nil
+	}
+	if err := ValidateCompileFlags(r.Options.CompileFlags); err != nil {
+		return "", &customErrors.CompilationError{Output: err.Error()}
+	}
+
+	compileResult, err := execInWorkspace(containerCpy, ctx, r.CompileCommandLine(fileName), r.env(fileName), nil)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return "", err
+	} else if err != nil {
+		return "", fmt.Errorf("failed to run compile command: %v", err)
+	}
+	if compileResult.ExitCode != 0 {
+		diagnostics := strings.TrimSpace(compileResult.Stdout + "\n" + compileResult.Stderr)
+		return "", &customErrors.CompilationError{Output: truncateOutput(diagnostics, maxDiagnosticsBytes)}
+	}
+	if err := r.removeGraderFiles(containerCpy, ctx); err != nil {
+		return "", err
+	}
+	return executableFileCommand, nil
+}
+
+// InstallArtifacts copies the artifacts of an identical earlier compile into
+// the workspace the code was copied to, in place of compiling it.
+func (r RegistryRunLangInterface) InstallArtifacts(containerCpy *models.Container, fileName string, artifacts map[string][]byte, ctx context.Context) (string, error) {
+	if err := r.CopyArtifacts(containerCpy, artifacts); err != nil {
+		return "", err
+	}
+	if err := r.removeGraderFiles(containerCpy, ctx); err != nil {
+		return "", err
+	}
+	return r.RunCommandLine(fileName), nil
+}
+
+// removeGraderFiles deletes the grader's files once they are compiled into the
+// program, so the submission cannot read them while it runs. A grader with an
+// entry is run itself and keeps its files.
+func (r RegistryRunLangInterface) removeGraderFiles(containerCpy *models.Container, ctx context.Context) error {
+	grader := r.Options.Grader
+	if grader == nil || grader.Entry != "" || len(grader.Files) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(grader.Files))
+	for name := range grader.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	result, err := execInWorkspace(containerCpy, ctx, append([]string{"rm", "-f", "--"}, names...), nil, nil)
+	if err != nil {
+		return fmt.Errorf("failed to remove grader files: %v", err)
+	}
+	if result.ExitCode != 0 {
+		return fmt.Errorf("failed to remove grader files: %s", strings.TrimSpace(result.Stderr))
+	}
+	return nil
+}
+
+func (r RegistryRunLangInterface) RunTestCases(containerCpy *models.Container, fileName string, testcase string, compileCommand string, ctx context.Context) (string, error) {
+	testcaseStart := time.Now()
+	cmdParts := strings.Fields(compileCommand)
+
+	env := r.env(fileName)
+	if r.restrictsModules() {
+		env = append(env, r.moduleRestrictionEnv()...)
+	}
+	stdin, err := r.testInput(containerCpy, testcase)
+	if err != nil {
+		return "", err
+	}
+	runResult, err := execInWorkspace(containerCpy, ctx, cmdParts, env, stdin)
+	if err != nil {
+		return "", err
+	}
+	if r.restrictsModules() {
+		if err := restrictedImport(runResult); err != nil {
+			return "", err
+		}
+	}
+	if runResult.ExitCode != 0 && (r.Options.Diagnostics || r.Options.RuntimeErrorOnNonZeroExit) {
+		runtimeErr := &customErrors.RuntimeError{ExitCode: runResult.ExitCode}
+		if r.Options.Diagnostics {
+			runtimeErr.Report = truncateOutput(strings.TrimSpace(runResult.Stderr), maxDiagnosticsBytes)
+		}
+		return "", runtimeErr
+	}
+
+	output, err := r.testOutput(containerCpy, runResult.Stdout)
+	if err != nil {
+		return "", err
+	}
+	cleanOutput := strings.TrimSpace(output)
+	if runResult.Stderr != "" {
+		fmt.Printf("Stderr for testcase: %s\n", runResult.Stderr)
+	}
+	testcaseTime := time.Since(testcaseStart)
+	fmt.Printf("Testcase (%s) completed in: %s. Output: '%s'\n", r.Spec.Key, testcaseTime, cleanOutput)
+	return cleanOutput, nil
+}
+
+// ReadArtifacts reads the files the spec lists as the compiled program from
+// the workspace. A pattern such as "*.class" stands for every file whose name
+// it matches, wherever in the workspace, as compilers put the output of a
+// project's files in their directories.
+func (r RegistryRunLangInterface) ReadArtifacts(containerCpy *models.Container, fileName string) (map[string][]byte, error) {
+	var names []string
+	var workspaceFiles []string
+	for _, pattern := range r.expand(r.Spec.Artifacts, fileName) {
+		if !isArtifactPattern(pattern) {
+			names = append(names, pattern)
+			continue
+		}
+		if workspaceFiles == nil {
+			var err error
+			workspaceFiles, err = containerCpy.Runtime.ListFiles(containerCpy.Ctx, containerCpy.SandboxID, workspaceDir)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list artifacts: %v", err)
+			}
+		}
+		matched := false
+		for _, name := range workspaceFiles {
+			if matchesArtifact(pattern, name) {
+				names = append(names, name)
+				matched = true
+			}
+		}
+		if !matched {
+			return nil, fmt.Errorf("no artifact matches %s", pattern)
+		}
+	}
+
+	artifacts := make(map[string][]byte, len(names))
+	for _, name := range names {
+		content, err := containerCpy.Runtime.ReadFile(containerCpy.Ctx, containerCpy.SandboxID, path.Join(workspaceDir, name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read artifact %s: %v", name, err)
+		}
+		artifacts[name] = content
+	}
+	return artifacts, nil
+}
+
+func isArtifactPattern(artifact string) bool {
+	return strings.ContainsAny(artifact, "*?[")
+}
+
+// matchesArtifact reports whether the workspace file name is one the artifact,
+// a file name or a pattern, stands for.
+func matchesArtifact(artifact string, name string) bool {
+	if !isArtifactPattern(artifact) {
+		return path.Clean(name) == path.Clean(artifact)
+	}
+	ok, _ := path.Match(artifact, path.Base(name))
+	return ok
+}
+
+func (r RegistryRunLangInterface) CopyArtifacts(containerCpy *models.Container, artifacts map[string][]byte) error {
+	return CopyArtifactsGlobalUtil(containerCpy, artifacts)
+}
+
+// diagnostics is the language's diagnostic mode when the submission asked for it.
+func (r RegistryRunLangInterface) diagnostics() *models.DiagnosticMode {
+	if !r.Options.Diagnostics {
+		return nil
+	}
+	return r.Spec.Diagnostics
+}
+
+// env is the environment of the compile and run commands.
+func (r RegistryRunLangInterface) env(fileName string) []string {
+	env := r.Spec.Env
+	if diagnostics := r.diagnostics(); diagnostics != nil {
+		env = append(append([]string(nil), env...), diagnostics.Env...)
+	}
+	return r.expand(env, fileName)
+}
+
+// expand substitutes the spec placeholders in a command template.
+func (r RegistryRunLangInterface) expand(template []string, fileName string) []string {
+	replacer := strings.NewReplacer(
+		"{source}", fileName,
+		"{class}", strings.TrimSuffix(fileName, filepath.Ext(fileName)),
+		"{memoryMB}", strconv.Itoa(r.Limit.MemoryLimitInMB),
+	)
+	args := make([]string, len(template))
+	for i, arg := range template {
+		args[i] = replacer.Replace(arg)
+	}
+	return args
+}
+
+// compileFlagPrefixes start the problem compile flags allowed: optimisation,
+// language standard, macros, warnings, debug information, target machine and
+// code generation options, none of which changes which files are read or
+// written. compileFlags are allowed as they are.
+var (
+	compileFlagPrefixes = []string{"-O", "-std=", "-D", "-U", "-W", "-g", "-m", "-f", "-pedantic"}
+	compileFlags        = []string{"-w", "-pthread"}
+)
+
+// deniedCompileFlagPrefixes are the exceptions among compileFlagPrefixes:
+// options passed on to the assembler, preprocessor or linker, compiler
+// plugins and options writing or reading profiles and dumps.
+var deniedCompileFlagPrefixes = []string{"-Wl,", "-Wa,", "-Wp,", "-fplugin", "-fprofile", "-fauto-profile", "-fdump", "-fopt-info", "-fcallgraph-info", "-fsave-optimization-record"}
+
+// ValidateCompileFlags rejects problem flags that could change what gets built
+// or where, rather than how: every flag must be one of compileFlags or start
+// with one of compileFlagPrefixes, and name no path, so output paths, response files, included files, search
+// directories, spec files and wrappers are all refused.
+func ValidateCompileFlags(flags []string) error {
+	for _, flag := range flags {
+		if !strings.HasPrefix(flag, "-") {
+			return fmt.Errorf("compile flag %q is not an option", flag)
+		}
+		allowed := slices.Contains(compileFlags, flag) || slices.ContainsFunc(compileFlagPrefixes, func(prefix string) bool { return strings.HasPrefix(flag, prefix) })
+		denied := slices.ContainsFunc(deniedCompileFlagPrefixes, func(prefix string) bool { return strings.HasPrefix(flag, prefix) })
+		if !allowed || denied || strings.Contains(flag, "/") {
+			return fmt.Errorf("compile flag %q is not allowed", flag)
+		}
+	}
+	return nil
+}
@@ -1,78 +1,91 @@
 package service
 
 import (
-	"archive/tar"
-	"bytes"
-	"encoding/binary"
+	"context"
 	"fmt"
-	"github.com/docker/docker/api/types/container"
-	"io"
+	"io/fs"
 	"judging-service/internal/models"
+	"judging-service/internal/sandbox"
+	"path"
+	"strings"
 )
 
-func CopyCodeToFileGlobalUtil(containerCpy *models.Container, fileName string, code string) (string, error) {
-	tarData, err := createTarArchiveFromMemory(fileName, code)
-	if err != nil {
-		return "", fmt.Errorf("failed to create tar archive: %v", err)
-	}
+const workspaceDir = "/workspace"
 
-	err = containerCpy.Cli.CopyToContainer(containerCpy.Ctx, containerCpy.ContainerResp.ID, "/workspace", tarData, container.CopyToContainerOptions{})
-	if err != nil {
-		return "", fmt.Errorf("failed to copy source to container: %v", err)
+// maxDiagnosticsBytes caps compiler output attached to a result.
+const maxDiagnosticsBytes = 4096
 
+// CopyCodeToFileGlobalUtil places a submission's files in the workspace, then
+// the problem's grader files, read-only and written last so that the
+// submission cannot replace them. Files named in graderFiles are skipped when
+// writing files.
+func CopyCodeToFileGlobalUtil(containerCpy *models.Container, files map[string]string, graderFiles map[string]string) error {
+	submitted := make(map[string]string, len(files))
+	for name, content := range files {
+		if _, ok := graderFiles[name]; !ok {
+			submitted[name] = content
+		}
 	}
-	return fileName, nil
-}
-
-func createTarArchiveFromMemory(filename, content string) (io.Reader, error) {
-	var buf bytes.Buffer
-	tw := tar.NewWriter(&buf)
-	fileContent := []byte(content)
-
-	header := &tar.Header{
-		Name: filename,
-		Mode: 0644,
-		Size: int64(len(fileContent)),
+	if err := CopySourceFilesGlobalUtil(containerCpy, submitted, 0644); err != nil {
+		return err
 	}
-
-	if err := tw.WriteHeader(header); err != nil {
-		return nil, fmt.Errorf("failed to write tar header: %v", err)
+	if len(graderFiles) == 0 {
+		return nil
 	}
+	return CopySourceFilesGlobalUtil(containerCpy, graderFiles, 0444)
+}
 
-	if _, err := tw.Write(fileContent); err != nil {
-		return nil, fmt.Errorf("failed to write file content to tar: %v", err)
+// CopySourceFilesGlobalUtil writes source files, keyed by their path relative
+// to the workspace, into the workspace.
+func CopySourceFilesGlobalUtil(containerCpy *models.Container, files map[string]string, mode fs.FileMode) error {
+	contents := make(map[string][]byte, len(files))
+	for name, content := range files {
+		if err := validateWorkspacePath(name); err != nil {
+			return err
+		}
+		contents[name] = []byte(content)
 	}
-
-	if err := tw.Close(); err != nil {
-		return nil, fmt.Errorf("failed to close tar writer: %v", err)
+	err := containerCpy.Runtime.CopyFiles(containerCpy.Ctx, containerCpy.SandboxID, workspaceDir, contents, mode)
+	if err != nil {
+		return fmt.Errorf("failed to copy source to container: %v", err)
 	}
-
-	return &buf, nil
+	return nil
 }
 
-func demultiplexDockerOutput(data []byte) (stdout, stderr string) {
-	var stdoutBuf, stderrBuf bytes.Buffer
-
-	for len(data) > 8 {
-		// Docker uses 8-byte headers: [stream_type][0][0][0][size_bytes]
-		streamType := data[0]
-		size := binary.BigEndian.Uint32(data[4:8])
-
-		if len(data) < 8+int(size) {
-			break
-		}
-
-		payload := data[8 : 8+size]
+// validateWorkspacePath rejects file names that would land outside the
+// workspace or in the judge's own directory.
+func validateWorkspacePath(name string) error {
+	clean := path.Clean(name)
+	if name == "" || path.IsAbs(name) || clean == "." || clean == ".." || strings.HasPrefix(clean, "../") ||
+		clean == importHookDir || strings.HasPrefix(clean, importHookDir+"/") {
+		return fmt.Errorf("invalid file name %q", name)
+	}
+	return nil
+}
 
-		switch streamType {
-		case 1: // stdout
-			stdoutBuf.Write(payload)
-		case 2: // stderr
-			stderrBuf.Write(payload)
-		}
+// execInWorkspace runs cmd in the container's workspace, feeding it stdin when non-nil.
+func execInWorkspace(containerCpy *models.Container, ctx context.Context, cmd []string, env []string, stdin []byte) (sandbox.ExecResult, error) {
+	return containerCpy.Runtime.Exec(ctx, containerCpy.SandboxID, sandbox.ExecRequest{
+		Cmd:        cmd,
+		Env:        env,
+		WorkingDir: workspaceDir,
+		Stdin:      stdin,
+	})
+}
 
-		data = data[8+size:]
+// CopyArtifactsGlobalUtil writes compiled files into the workspace, executable.
+func CopyArtifactsGlobalUtil(containerCpy *models.Container, artifacts map[string][]byte) error {
+	err := containerCpy.Runtime.CopyFiles(containerCpy.Ctx, containerCpy.SandboxID, workspaceDir, artifacts, 0755)
+	if err != nil {
+		return fmt.Errorf("failed to copy artifacts to container: %v", err)
 	}
+	return nil
+}
 
-	return stdoutBuf.String(), stderrBuf.String()
+// truncateOutput shortens s to at most limit bytes, marking the cut.
+func truncateOutput(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+	return s[:limit] + "\n... (truncated)"
 }
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"fmt"
+	"judging-service/internal/models"
+	"path"
+	"slices"
+	"strings"
+)
+
+// ValidateFileIO checks the submission's I/O file names and that neither names
+// a file of the program: its source, driver, grader or project files, its
+// artifacts or a file its compile and run commands name, e.g. "solution".
+func (r RegistryRunLangInterface) ValidateFileIO(code string) error {
+	fileIO := r.Options.FileIO
+	if fileIO == nil {
+		return nil
+	}
+	if err := validateFileIO(*fileIO); err != nil {
+		return err
+	}
+	fileName := r.SourceFileName(code)
+	taken := make(map[string]bool)
+	if files, err := r.SourceFiles(code); err == nil {
+		for name := range files {
+			taken[path.Clean(name)] = true
+		}
+	}
+	for _, name := range append(r.CompileCommandLine(fileName), strings.Fields(r.RunCommandLine(fileName))...) {
+		if !strings.HasPrefix(name, "-") {
+			taken[path.Clean(name)] = true
+		}
+	}
+	artifacts := r.expand(r.Spec.Artifacts, fileName)
+	for _, name := range []string{fileIO.Input, fileIO.Output} {
+		if name == "" {
+			continue
+		}
+		isArtifact := slices.ContainsFunc(artifacts, func(artifact string) bool {
+			return matchesArtifact(artifact, name)
+		})
+		if taken[path.Clean(name)] || isArtifact {
+			return fmt.Errorf("the I/O file %q is a file of the program", name)
+		}
+	}
+	return nil
+}
+
+// validateFileIO checks that the I/O file names stay in the workspace and
+// tell input and output apart.
+func validateFileIO(fileIO models.FileIO) error {
+	for _, name := range []string{fileIO.Input, fileIO.Output} {
+		if name == "" {
+			continue
+		}
+		if err := validateWorkspacePath(name); err != nil {
+			return err
+		}
+	}
+	if fileIO.Input != "" && path.Clean(fileIO.Input) == path.Clean(fileIO.Output) {
+		return fmt.Errorf("the input and output files are both %q", fileIO.Input)
+	}
+	return nil
+}
+
+// testInput places the test case where the program reads it and returns what
+// to feed its stdin. The output file is emptied beforehand, writable by the
+// program, so one left by an earlier run in the sandbox is never read back.
+func (r RegistryRunLangInterface) testInput(containerCpy *models.Container, testcase string) ([]byte, error) {
+	fileIO := r.Options.FileIO
+	if fileIO == nil {
+		return []byte(testcase + "\n"), nil
+	}
+	if err := validateFileIO(*fileIO); err != nil {
+		return nil, err
+	}
+	if fileIO.Output != "" {
+		if err := CopySourceFilesGlobalUtil(containerCpy, map[string]string{fileIO.Output: ""}, 0666); err != nil {
+			return nil, err
+		}
+	}
+	if fileIO.Input == "" {
+		return []byte(testcase + "\n"), nil
+	}
+	if err := CopySourceFilesGlobalUtil(containerCpy, map[string]string{fileIO.Input: testcase + "\n"}, 0644); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// testOutput is what the program wrote to the output file, or to stdout.
+func (r RegistryRunLangInterface) testOutput(containerCpy *models.Container, stdout string) (string, error) {
+	fileIO := r.Options.FileIO
+	if fileIO == nil || fileIO.Output == "" {
+		return stdout, nil
+	}
+	content, err := containerCpy.Runtime.ReadFile(containerCpy.Ctx, containerCpy.SandboxID, path.Join(workspaceDir, fileIO.Output))
+	if err != nil {
+		return "", fmt.Errorf("failed to read output file %s: %v", fileIO.Output, err)
+	}
+	return string(content), nil
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	_ "embed"
+	"fmt"
+	"judging-service/internal/customErrors"
+	"judging-service/internal/models"
+	"judging-service/internal/project"
+	"judging-service/internal/sandbox"
+	"path"
+	"sort"
+	"strings"
+)
+
+// pythonImportHook is preloaded into the interpreter through PYTHONPATH and
+// enforces the allowed and denied modules passed in its environment.
+//
+//go:embed python/sitecustomize.py
+var pythonImportHook []byte
+
+const (
+	// importHookDir is the workspace directory holding the import hook.
+	importHookDir = project.ReservedDir
+	// restrictedImportExitCode and restrictedImportMarker must match sitecustomize.py.
+	restrictedImportExitCode = 120
+	restrictedImportMarker   = "judge: restricted import: "
+)
+
+// restrictsModules reports whether the submission's module restrictions
+// apply, which they do only for languages that can enforce them.
+func (r RegistryRunLangInterface) restrictsModules() bool {
+	return r.Spec.ModuleRestrictions && (len(r.Options.AllowedModules) > 0 || len(r.Options.DeniedModules) > 0)
+}
+
+func copyImportHook(containerCpy *models.Container) error {
+	files := map[string][]byte{path.Join(importHookDir, "sitecustomize.py"): pythonImportHook}
+	if err := containerCpy.Runtime.CopyFiles(containerCpy.Ctx, containerCpy.SandboxID, workspaceDir, files, 0644); err != nil {
+		return fmt.Errorf("failed to copy import hook to container: %v", err)
+	}
+	return nil
+}
+
+// moduleRestrictionEnv loads the import hook and passes it the restrictions.
+// The grader's files are problem code, so their imports are not checked.
+func (r RegistryRunLangInterface) moduleRestrictionEnv() []string {
+	var trusted []string
+	if r.Options.Grader != nil {
+		for name := range r.Options.Grader.Files {
+			trusted = append(trusted, name)
+		}
+		sort.Strings(trusted)
+	}
+	return []string{
+		// Relative to the workspace, where the program starts.
+		"PYTHONPATH=" + importHookDir,
+		"JUDGE_ALLOWED_MODULES=" + strings.Join(r.Options.AllowedModules, ","),
+		"JUDGE_DENIED_MODULES=" + strings.Join(r.Options.DeniedModules, ","),
+		"JUDGE_TRUSTED_FILES=" + strings.Join(trusted, ","),
+	}
+}
+
+// restrictedImport recognises a run the import hook ended, returning the error
+// naming the restricted module.
+func restrictedImport(result sandbox.ExecResult) error {
+	if result.ExitCode != restrictedImportExitCode {
+		return nil
+	}
+	for _, line := range strings.Split(result.Stderr, "\n") {
+		if module, ok := strings.CutPrefix(line, restrictedImportMarker); ok {
+			return &customErrors.RestrictedFunctionError{Function: "import " + strings.TrimSpace(module)}
+		}
+	}
+	return nil
+}
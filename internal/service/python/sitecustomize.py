@@ -0,0 +1,118 @@
+# Enforces a problem's module restrictions on a Python submission.
+#
+# The judge puts this file's directory on PYTHONPATH, so the interpreter
+# imports it before the submission, and passes the restrictions as
+# comma-separated module names in JUDGE_ALLOWED_MODULES and
+# JUDGE_DENIED_MODULES. A module is restricted when an allow list is given and
+# neither it nor a parent package is on it, or when it or a parent package is
+# denied. Only imports made by the submission's own files are checked, so the
+# standard library can still import what it needs internally; the problem's
+# grader files, listed in JUDGE_TRUSTED_FILES relative to the workspace, are
+# not the submission's. The importing file is found from the call stack, not
+# from the globals an import is given, and code compiled from a string, such
+# as exec('import os', {}), counts as the file that compiled it.
+#
+# Import statements go through builtins.__import__ and importlib.import_module,
+# which are wrapped so that they see modules that are already loaded too; an
+# audit hook, which cannot be removed, catches other ways of loading a
+# module. This is a policy check, not a security boundary: the sandbox is
+# that. A restricted import ends the program with
+# RESTRICTED_EXIT_CODE after writing the marker line the judge looks for.
+import builtins
+import importlib
+import os
+import sys
+
+RESTRICTED_EXIT_CODE = 120
+
+_allowed = [m for m in os.environ.pop("JUDGE_ALLOWED_MODULES", "").split(",") if m]
+_denied = [m for m in os.environ.pop("JUDGE_DENIED_MODULES", "").split(",") if m]
+_workspace = os.getcwd()
+_trusted = {
+    os.path.join(_workspace, os.path.normpath(f))
+    for f in os.environ.pop("JUDGE_TRUSTED_FILES", "").split(",")
+    if f
+}
+_hook_files = {os.path.abspath(__file__), os.path.abspath(importlib.__file__)}
+
+
+def _matches(name, modules):
+    return any(name == m or name.startswith(m + ".") for m in modules)
+
+
+def _restricted(name):
+    if _allowed and not _matches(name, _allowed):
+        return True
+    return _matches(name, _denied)
+
+
+_import_machinery = {"<frozen importlib._bootstrap>", "<frozen importlib._bootstrap_external>"}
+
+
+def _is_hook(filename):
+    return filename in _import_machinery or os.path.abspath(filename) in _hook_files
+
+
+def _from_string(filename):
+    # Code compiled from a string has a name like "<string>"; frozen standard
+    # library modules, named "<frozen ...>", are not.
+    return filename.startswith("<") and not filename.startswith("<frozen ")
+
+
+def _importing_file(frame):
+    # The importing file is that of the first frame not compiled from a string.
+    while frame is not None and _from_string(frame.f_code.co_filename):
+        frame = frame.f_back
+    return frame.f_code.co_filename if frame is not None else None
+
+
+def _from_submission(filename):
+    if not filename or filename.startswith("<") or _is_hook(filename):
+        return False
+    path = os.path.abspath(filename)
+    return path.startswith(_workspace + os.sep) and path not in _trusted
+
+
+def _reject(name):
+    # Written to the file descriptor, as the submission may replace sys.stderr.
+    os.write(2, ("judge: restricted import: %s\n" % name).encode())
+    os._exit(RESTRICTED_EXIT_CODE)
+
+
+_import = builtins.__import__
+
+
+def _checked_import(name, globals=None, locals=None, fromlist=(), level=0):
+    if level == 0 and _restricted(name) and _from_submission(_importing_file(sys._getframe(1))):
+        _reject(name)
+    return _import(name, globals, locals, fromlist, level)
+
+
+_import_module = importlib.import_module
+
+
+def _checked_import_module(name, package=None):
+    if not name.startswith(".") and _restricted(name) and _from_submission(_importing_file(sys._getframe(1))):
+        _reject(name)
+    return _import_module(name, package)
+
+
+def _audit(event, args):
+    if event != "import":
+        return
+    # The importing code is the first frame outside the import machinery.
+    frame = sys._getframe(1)
+    while frame is not None and _is_hook(frame.f_code.co_filename):
+        frame = frame.f_back
+    if _restricted(args[0]) and _from_submission(_importing_file(frame)):
+        _reject(args[0])
+
+
+if _allowed or _denied:
+    builtins.__import__ = _checked_import
+    importlib.import_module = _checked_import_module
+    if hasattr(sys, "addaudithook"):
+        sys.addaudithook(_audit)
+# The hook's directory is not meant for the submission's own imports.
+_hook_dir = os.path.dirname(os.path.abspath(__file__))
+sys.path[:] = [p for p in sys.path if os.path.abspath(p) != _hook_dir]
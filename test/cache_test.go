@@ -0,0 +1,53 @@
+package processorpackage
+
+import (
+	"bytes"
+	"judging-service/internal/cache"
+	"testing"
+)
+
+func TestArtifactCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	dir := t.TempDir()
+	artifact := func(b byte) map[string][]byte {
+		return map[string][]byte{"solution": bytes.Repeat([]byte{b}, 1000)}
+	}
+	// Room for two entries, not three.
+	artifacts, err := cache.New(dir, 2500)
+	if err != nil {
+		t.Fatalf("open cache: %v", err)
+	}
+
+	first, second, third := cache.Key("a", "cpp"), cache.Key("b", "cpp"), cache.Key("a", "cpp17")
+	if first == third {
+		t.Fatalf("Expected different language variants to have different keys")
+	}
+	for key, b := range map[string]byte{first: 1, second: 2} {
+		if err := artifacts.Put(key, artifact(b)); err != nil {
+			t.Fatalf("put: %v", err)
+		}
+	}
+	// Using the first entry leaves the second as the least recently used.
+	if files, ok := artifacts.Get(first); !ok || !bytes.Equal(files["solution"], artifact(1)["solution"]) {
+		t.Fatalf("Expected the first entry to be cached")
+	}
+	if err := artifacts.Put(third, artifact(3)); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if _, ok := artifacts.Get(second); ok {
+		t.Errorf("Expected the least recently used entry to be evicted")
+	}
+	if artifacts.Size() > 2500 {
+		t.Errorf("Expected the cache to stay within its bound, but it holds %d bytes", artifacts.Size())
+	}
+
+	// Entries survive reopening the cache.
+	reopened, err := cache.New(dir, 2500)
+	if err != nil {
+		t.Fatalf("reopen cache: %v", err)
+	}
+	for _, key := range []string{first, third} {
+		if _, ok := reopened.Get(key); !ok {
+			t.Errorf("Expected entry %s to survive reopening", key)
+		}
+	}
+}
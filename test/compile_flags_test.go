@@ -0,0 +1,42 @@
+package processorpackage
+
+import (
+	"judging-service/internal/service"
+	"testing"
+)
+
+func TestValidateCompileFlags(t *testing.T) {
+	testCases := []struct {
+		name    string
+		flags   []string
+		allowed bool
+	}{
+		{name: "Defaults", flags: []string{"-O2", "-std=c++17", "-DONLINE_JUDGE"}, allowed: true},
+		{name: "Warnings And Code Generation", flags: []string{"-Wall", "-Wextra", "-w", "-fno-exceptions", "-fsanitize=address", "-march=native", "-g", "-pedantic-errors", "-UNDEBUG", "-pthread"}, allowed: true},
+		{name: "No Flags", flags: nil, allowed: true},
+		{name: "Output Path", flags: []string{"-o/tmp/elsewhere"}},
+		{name: "Linker Output Path", flags: []string{"-Wl,-o,/x"}},
+		{name: "Assembler Options", flags: []string{"-Wa,-alh=listing"}},
+		{name: "Wrapper", flags: []string{"-wrapper", "gdb"}},
+		{name: "Tool Directory", flags: []string{"-B/tmp"}},
+		{name: "Spec File", flags: []string{"-specs=x"}},
+		{name: "Forced Include", flags: []string{"-include", "/abs/path"}},
+		{name: "Include Directory", flags: []string{"-I/etc"}},
+		{name: "Response File", flags: []string{"@file"}},
+		{name: "Plugin", flags: []string{"-fplugin=./plugin.so"}},
+		{name: "Profile Path", flags: []string{"-fprofile-generate=/tmp/profile"}},
+		{name: "Macro Naming A Path", flags: []string{"-DINPUT=/etc/passwd"}},
+		{name: "Not An Option", flags: []string{"main.cpp"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := service.ValidateCompileFlags(tc.flags)
+			if tc.allowed && err != nil {
+				t.Errorf("Expected flags %q to be allowed, but got: %v", tc.flags, err)
+			} else if !tc.allowed && err == nil {
+				t.Errorf("Expected flags %q to be refused", tc.flags)
+			}
+		})
+	}
+}
@@ -0,0 +1,1150 @@
+package processorpackage
+
+import (
+	"bytes"
+	"context"
+	"debug/elf"
+	"judging-service/api/Dtos"
+	"judging-service/containers"
+	"judging-service/internal/cache"
+	"judging-service/internal/models"
+	"judging-service/internal/policy"
+	"judging-service/internal/processor"
+	"judging-service/internal/registry"
+	"judging-service/internal/sandbox"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// These tests run the processor and pool against sandbox.FakeRuntime, which
+// executes local processes; a case is skipped when its toolchain is not installed.
+
+func TestRunCodeWithFakeRuntime(t *testing.T) {
+	fakeManager := containers.NewContainersPoolMangerWithRuntime(4, sandbox.NewFakeRuntime())
+	defer fakeManager.Close()
+
+	testCases := []struct {
+		name            string
+		submission      Dtos.SubmissionQueueDto
+		expectErr       bool
+		errContains     string
+		expectedVerdict int
+		expectedOutputs []string
+		diagnostics     string
+		report          string
+		commandContains string
+		requires        string
+	}{
+		{
+			name: "C++ Input and Output",
+			submission: Dtos.SubmissionQueueDto{
+				SubmissionId: 1,
+				Code:         "#include <iostream>\nint main() { int a, b; std::cin >> a >> b; std::cout << a + b; }",
+				Language:     1,
+				MemoryLimit:  256,
+				TimeLimit:    2.0,
+				InputTests: []models.TestCaseInput{
+					{TestCaseId: 1, Input: "3 4"},
+					{TestCaseId: 2, Input: "10 20"},
+				},
+			},
+			expectedOutputs: []string{"7", "30"},
+			requires:        "g++",
+		},
+		{
+			name: "C++ Compilation Error",
+			submission: Dtos.SubmissionQueueDto{
+				SubmissionId: 2,
+				Code:         "int main() { undeclared_variable = 5; }",
+				Language:     1,
+				MemoryLimit:  256,
+				TimeLimit:    1.0,
+				InputTests:   []models.TestCaseInput{{TestCaseId: 1, Input: ""}},
+			},
+			expectErr:       true,
+			errContains:     "compilation failed",
+			expectedVerdict: 3,
+			diagnostics:     "undeclared_variable",
+			requires:        "g++",
+		},
+		{
+			name: "Python Input and Output",
+			submission: Dtos.SubmissionQueueDto{
+				SubmissionId: 3,
+				Code:         "name = input()\nprint(f\"Hello {name}!\")",
+				Language:     0,
+				MemoryLimit:  256,
+				TimeLimit:    2.0,
+				InputTests: []models.TestCaseInput{
+					{TestCaseId: 1, Input: "World"},
+				},
+			},
+			expectedOutputs: []string{"Hello World!"},
+			requires:        "python",
+		},
+		{
+			name: "Python Time Limit Exceeded",
+			submission: Dtos.SubmissionQueueDto{
+				SubmissionId: 4,
+				Code:         "while True:\n    pass",
+				Language:     0,
+				MemoryLimit:  256,
+				TimeLimit:    1.0,
+				InputTests:   []models.TestCaseInput{{TestCaseId: 1, Input: ""}},
+			},
+			expectErr:       true,
+			errContains:     "Time Limit",
+			expectedVerdict: 2,
+			requires:        "python",
+		},
+		{
+			name: "Go Standard Library Only",
+			submission: Dtos.SubmissionQueueDto{
+				SubmissionId: 5,
+				Code:         "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tvar a, b int\n\tfmt.Scan(&a, &b)\n\tfmt.Println(a * b)\n}\n",
+				Language:     3,
+				MemoryLimit:  256,
+				TimeLimit:    2.0,
+				InputTests:   []models.TestCaseInput{{TestCaseId: 1, Input: "6 7"}},
+			},
+			expectedOutputs: []string{"42"},
+			requires:        "go",
+		},
+		{
+			name: "Go Third Party Import Is Rejected",
+			submission: Dtos.SubmissionQueueDto{
+				SubmissionId: 6,
+				Code:         "package main\n\nimport \"github.com/pkg/errors\"\n\nfunc main() { _ = errors.New(\"x\") }\n",
+				Language:     3,
+				MemoryLimit:  256,
+				TimeLimit:    2.0,
+				InputTests:   []models.TestCaseInput{{TestCaseId: 1, Input: ""}},
+			},
+			expectErr:       true,
+			errContains:     "compilation failed",
+			expectedVerdict: 3,
+			requires:        "go",
+		},
+		{
+			name: "Rust Input and Output",
+			submission: Dtos.SubmissionQueueDto{
+				SubmissionId: 7,
+				Code:         "use std::io::Read;\nfn main() {\n    let mut s = String::new();\n    std::io::stdin().read_to_string(&mut s).unwrap();\n    let n: i64 = s.split_whitespace().map(|x| x.parse::<i64>().unwrap()).sum();\n    println!(\"{}\", n);\n}\n",
+				Language:     4,
+				MemoryLimit:  256,
+				TimeLimit:    2.0,
+				InputTests:   []models.TestCaseInput{{TestCaseId: 1, Input: "1 2 3"}},
+			},
+			expectedOutputs: []string{"6"},
+			requires:        "rustc",
+		},
+		{
+			name: "JavaScript Reads Stdin",
+			submission: Dtos.SubmissionQueueDto{
+				SubmissionId: 8,
+				Code:         "const lines = require('fs').readFileSync(0, 'utf8').trim().split(' ');\nconsole.log(lines.map(Number).reduce((a, b) => a + b, 0));\n",
+				Language:     5,
+				MemoryLimit:  256,
+				TimeLimit:    2.0,
+				InputTests:   []models.TestCaseInput{{TestCaseId: 1, Input: "4 5 6"}},
+			},
+			expectedOutputs: []string{"15"},
+			requires:        "node",
+		},
+		{
+			name: "JavaScript Syntax Error",
+			submission: Dtos.SubmissionQueueDto{
+				SubmissionId: 9,
+				Code:         "console.log((1 + 2);\n",
+				Language:     5,
+				MemoryLimit:  256,
+				TimeLimit:    2.0,
+				InputTests:   []models.TestCaseInput{{TestCaseId: 1, Input: ""}},
+			},
+			expectErr:       true,
+			errContains:     "compilation failed",
+			expectedVerdict: 3,
+			requires:        "node",
+		},
+		{
+			name: "C Input and Output",
+			submission: Dtos.SubmissionQueueDto{
+				SubmissionId: 10,
+				Code:         "#include <stdio.h>\n#include <math.h>\nint main(void) { double x; scanf(\"%lf\", &x); printf(\"%.0f\\n\", sqrt(x)); return 0; }\n",
+				Language:     7,
+				MemoryLimit:  256,
+				TimeLimit:    2.0,
+				InputTests:   []models.TestCaseInput{{TestCaseId: 1, Input: "49"}},
+			},
+			expectedOutputs: []string{"7"},
+			requires:        "gcc",
+		},
+		{
+			name: "C Runs Without Its Source In The Run Sandbox",
+			submission: Dtos.SubmissionQueueDto{
+				SubmissionId: 14,
+				Code:         "#include <stdio.h>\nint main(void) { puts(fopen(\"main.c\", \"r\") ? \"present\" : \"absent\"); return 0; }\n",
+				Language:     7,
+				MemoryLimit:  256,
+				TimeLimit:    2.0,
+				InputTests: []models.TestCaseInput{
+					{TestCaseId: 1, Input: ""},
+					{TestCaseId: 2, Input: ""},
+				},
+			},
+			expectedOutputs: []string{"absent", "absent"},
+			requires:        "gcc",
+		},
+		{
+			name: "C++ Defines ONLINE_JUDGE By Default",
+			submission: Dtos.SubmissionQueueDto{
+				SubmissionId: 11,
+				Code:         "#include <cstdio>\nint main() {\n#ifdef ONLINE_JUDGE\n  std::puts(\"judge\");\n#else\n  std::puts(\"local\");\n#endif\n}\n",
+				Language:     10,
+				MemoryLimit:  256,
+				TimeLimit:    2.0,
+				InputTests:   []models.TestCaseInput{{TestCaseId: 1, Input: ""}},
+			},
+			expectedOutputs: []string{"judge"},
+			commandContains: "g++ -O2 -std=c++17 -DONLINE_JUDGE -static -o solution main.cpp",
+			requires:        "g++",
+		},
+		{
+			name: "C++ Problem Flags Replace Defaults",
+			submission: Dtos.SubmissionQueueDto{
+				SubmissionId: 12,
+				Code:         "#include <cstdio>\nint main() {\n#ifdef ONLINE_JUDGE\n  std::puts(\"judge\");\n#else\n  std::puts(\"local\");\n#endif\n}\n",
+				Language:     10,
+				MemoryLimit:  256,
+				TimeLimit:    2.0,
+				InputTests:   []models.TestCaseInput{{TestCaseId: 1, Input: ""}},
+				CompileFlags: []string{"-O0", "-std=c++20"},
+			},
+			expectedOutputs: []string{"local"},
+			commandContains: "g++ -O0 -std=c++20 -static -o solution main.cpp",
+			requires:        "g++",
+		},
+		{
+			name: "C++ Problem Flags Cannot Redirect Output",
+			submission: Dtos.SubmissionQueueDto{
+				SubmissionId: 13,
+				Code:         "int main() {}",
+				Language:     10,
+				MemoryLimit:  256,
+				TimeLimit:    2.0,
+				InputTests:   []models.TestCaseInput{{TestCaseId: 1, Input: ""}},
+				CompileFlags: []string{"-o/tmp/elsewhere"},
+			},
+			expectErr:       true,
+			errContains:     "compilation failed",
+			expectedVerdict: 3,
+			diagnostics:     "not allowed",
+			requires:        "g++",
+		},
+		{
+			name: "C++ Diagnostic Run Reports The Sanitizer Finding",
+			submission: Dtos.SubmissionQueueDto{
+				SubmissionId: 15,
+				Code:         "#include <vector>\nint main() { std::vector<int> v(3); return v.data()[5]; }\n",
+				Language:     10,
+				MemoryLimit:  256,
+				TimeLimit:    2.0,
+				InputTests:   []models.TestCaseInput{{TestCaseId: 1, Input: ""}},
+				CompileFlags: []string{"-O2"},
+				Diagnostics:  true,
+			},
+			expectErr:       true,
+			errContains:     "Runtime error",
+			expectedVerdict: 3,
+			report:          "AddressSanitizer: heap-buffer-overflow",
+			commandContains: "-fsanitize=address,undefined",
+			requires:        "g++",
+		},
+		{
+			name: "Python Diagnostic Run Reports The Traceback",
+			submission: Dtos.SubmissionQueueDto{
+				SubmissionId: 16,
+				Code:         "n = int(input())\nprint(10 // n)\n",
+				Language:     0,
+				MemoryLimit:  256,
+				TimeLimit:    2.0,
+				InputTests:   []models.TestCaseInput{{TestCaseId: 1, Input: "0"}},
+				Diagnostics:  true,
+			},
+			expectErr:       true,
+			errContains:     "Runtime error",
+			expectedVerdict: 3,
+			report:          "ZeroDivisionError",
+			requires:        "python",
+		},
+		{
+			name: "Python Runtime Error Is Not Reported Outside Diagnostic Runs",
+			submission: Dtos.SubmissionQueueDto{
+				SubmissionId:              17,
+				Code:                      "n = int(input())\nprint(10 // n)\n",
+				Language:                  0,
+				MemoryLimit:               256,
+				TimeLimit:                 2.0,
+				InputTests:                []models.TestCaseInput{{TestCaseId: 1, Input: "0"}},
+				RuntimeErrorOnNonZeroExit: true,
+			},
+			expectErr:       true,
+			errContains:     "Runtime error",
+			expectedVerdict: 3,
+			requires:        "python",
+		},
+		{
+			name: "Python Allowed Modules Can Be Imported",
+			submission: Dtos.SubmissionQueueDto{
+				SubmissionId:   18,
+				Code:           "import math\nfrom collections import Counter\nprint(math.isqrt(int(input())), len(Counter('aab')))\n",
+				Language:       0,
+				MemoryLimit:    256,
+				TimeLimit:      2.0,
+				InputTests:     []models.TestCaseInput{{TestCaseId: 1, Input: "17"}},
+				AllowedModules: []string{"math", "collections"},
+			},
+			expectedOutputs: []string{"4 2"},
+			requires:        "python",
+		},
+		{
+			name: "Python Denied Module Is A Restricted Function",
+			submission: Dtos.SubmissionQueueDto{
+				SubmissionId:  19,
+				Code:          "import sys\nimport os\nprint(os.listdir('/'))\n",
+				Language:      0,
+				MemoryLimit:   256,
+				TimeLimit:     2.0,
+				InputTests:    []models.TestCaseInput{{TestCaseId: 1, Input: ""}},
+				DeniedModules: []string{"os"},
+			},
+			expectErr:       true,
+			errContains:     "Restricted function: import os",
+			expectedVerdict: 3,
+			requires:        "python",
+		},
+		{
+			name: "Python Module Outside The Allow List Is A Restricted Function",
+			submission: Dtos.SubmissionQueueDto{
+				SubmissionId:   20,
+				Code:           "import importlib\nprint(importlib.import_module('subprocess'))\n",
+				Language:       0,
+				MemoryLimit:    256,
+				TimeLimit:      2.0,
+				InputTests:     []models.TestCaseInput{{TestCaseId: 1, Input: ""}},
+				AllowedModules: []string{"importlib"},
+			},
+			expectErr:       true,
+			errContains:     "Restricted function: import subprocess",
+			expectedVerdict: 3,
+			requires:        "python",
+		},
+		{
+			name: "Python Denied Module Through __import__ Is A Restricted Function",
+			submission: Dtos.SubmissionQueueDto{
+				SubmissionId:  42,
+				Code:          "m = __import__('os')\nprint(m.getcwd())\n",
+				Language:      0,
+				MemoryLimit:   256,
+				TimeLimit:     2.0,
+				InputTests:    []models.TestCaseInput{{TestCaseId: 1, Input: ""}},
+				DeniedModules: []string{"os", "subprocess"},
+			},
+			expectErr:       true,
+			errContains:     "Restricted function: import os",
+			expectedVerdict: 3,
+			requires:        "python",
+		},
+		{
+			name: "Python Denied Module Imported By Exec Is A Restricted Function",
+			submission: Dtos.SubmissionQueueDto{
+				SubmissionId:  43,
+				Code:          "exec('import subprocess', {})\n",
+				Language:      0,
+				MemoryLimit:   256,
+				TimeLimit:     2.0,
+				InputTests:    []models.TestCaseInput{{TestCaseId: 1, Input: ""}},
+				DeniedModules: []string{"os", "subprocess"},
+			},
+			expectErr:       true,
+			errContains:     "Restricted function: import subprocess",
+			expectedVerdict: 3,
+			requires:        "python",
+		},
+		{
+			name: "C++ Policy Violation Is Rejected Before Compiling",
+			submission: Dtos.SubmissionQueueDto{
+				SubmissionId: 21,
+				Code:         "#include <algorithm>\nint main() { int a[] = {2, 1}; std::sort(a, a + 2); }\n",
+				Language:     10,
+				MemoryLimit:  256,
+				TimeLimit:    2.0,
+				InputTests:   []models.TestCaseInput{{TestCaseId: 1, Input: ""}},
+				Policy: map[string]policy.Rules{
+					"cpp":    {ForbiddenHeaders: []string{"algorithm"}},
+					"python": {ForbiddenFunctions: []string{"sorted"}},
+				},
+			},
+			expectErr:       true,
+			errContains:     "Policy violation: line 1: header <algorithm> is forbidden",
+			expectedVerdict: 3,
+			requires:        "g++",
+		},
+		{
+			name: "Python Function Runs In The Problem Driver",
+			submission: Dtos.SubmissionQueueDto{
+				SubmissionId: 22,
+				Code:         "def add(a, b):\n    return a + b\n",
+				Language:     0,
+				MemoryLimit:  256,
+				TimeLimit:    2.0,
+				InputTests:   []models.TestCaseInput{{TestCaseId: 1, Input: "3 4"}},
+				Drivers: map[string]models.Driver{
+					"python": {Template: "{{solution}}\na, b = map(int, input().split())\nprint(add(a, b))\n"},
+				},
+			},
+			expectedOutputs: []string{"7"},
+			requires:        "python",
+		},
+		{
+			name: "C++ Function Is Compiled With The Problem Driver And Its Header",
+			submission: Dtos.SubmissionQueueDto{
+				SubmissionId: 23,
+				Code:         "long long solve(const std::vector<long long>& v) { long long s = 0; for (auto x : v) s += x; return s; }\n",
+				Language:     10,
+				MemoryLimit:  256,
+				TimeLimit:    2.0,
+				InputTests:   []models.TestCaseInput{{TestCaseId: 1, Input: "3\n1 2 3"}},
+				Drivers: map[string]models.Driver{
+					"cpp": {
+						Template: "#include \"io.h\"\n{{solution}}\nint main() { std::cout << solve(readVector()) << std::endl; }\n",
+						Files: map[string]string{
+							"io.h": "#include <iostream>\n#include <vector>\ninline std::vector<long long> readVector() { int n; std::cin >> n; std::vector<long long> v(n); for (auto& x : v) std::cin >> x; return v; }\n",
+						},
+					},
+				},
+			},
+			expectedOutputs: []string{"6"},
+			requires:        "g++",
+		},
+		{
+			name: "C++ Task Is Linked With The Problem Grader",
+			submission: Dtos.SubmissionQueueDto{
+				SubmissionId: 24,
+				Code:         "#include \"task.h\"\nlong long add(long long a, long long b) { return a + b; }\n",
+				Language:     10,
+				MemoryLimit:  256,
+				TimeLimit:    2.0,
+				InputTests:   []models.TestCaseInput{{TestCaseId: 1, Input: "3 4"}},
+				Graders: map[string]models.Grader{
+					"cpp": {
+						Files: map[string]string{
+							"task.h":     "long long add(long long a, long long b);\n",
+							"grader.cpp": "#include <cstdio>\n#include \"task.h\"\nint main() { long long a, b; std::scanf(\"%lld %lld\", &a, &b); std::printf(\"%lld\\n\", add(a, b)); }\n",
+						},
+						Sources:        []string{"grader.cpp"},
+						SubmissionFile: "task.cpp",
+					},
+				},
+			},
+			expectedOutputs: []string{"7"},
+			commandContains: "task.cpp grader.cpp",
+			requires:        "g++",
+		},
+		{
+			name: "Python Grader Imports The Task And May Use Modules The Submission Cannot",
+			submission: Dtos.SubmissionQueueDto{
+				SubmissionId:  25,
+				Code:          "def add(a, b):\n    return a + b\n",
+				Language:      0,
+				MemoryLimit:   256,
+				TimeLimit:     2.0,
+				InputTests:    []models.TestCaseInput{{TestCaseId: 1, Input: "3 4"}},
+				DeniedModules: []string{"sys"},
+				Graders: map[string]models.Grader{
+					"python": {
+						Files: map[string]string{
+							"grader.py": "import sys\nimport task\na, b = map(int, sys.stdin.readline().split())\nprint(task.add(a, b))\n",
+						},
+						Sources:        []string{"grader.py"},
+						Entry:          "grader.py",
+						SubmissionFile: "task.py",
+					},
+				},
+			},
+			expectedOutputs: []string{"7"},
+			requires:        "python",
+		},
+		{
+			name: "Python Task Is Still Restricted Under The Grader",
+			submission: Dtos.SubmissionQueueDto{
+				SubmissionId:  26,
+				Code:          "import sys\ndef add(a, b):\n    return a + b\n",
+				Language:      0,
+				MemoryLimit:   256,
+				TimeLimit:     2.0,
+				InputTests:    []models.TestCaseInput{{TestCaseId: 1, Input: "3 4"}},
+				DeniedModules: []string{"sys"},
+				Graders: map[string]models.Grader{
+					"python": {
+						Files:          map[string]string{"grader.py": "import task\nprint(task.add(*map(int, input().split())))\n"},
+						Entry:          "grader.py",
+						SubmissionFile: "task.py",
+					},
+				},
+			},
+			expectErr:       true,
+			errContains:     "Restricted function: import sys",
+			expectedVerdict: 3,
+			requires:        "python",
+		},
+		{
+			name: "C++ Project Archive Compiles Every Source File",
+			submission: Dtos.SubmissionQueueDto{
+				SubmissionId: 27,
+				Archive: zipArchive(t,
+					[2]string{"main.cpp", "#include <cstdio>\n#include \"util.h\"\nint main() { int a, b; std::scanf(\"%d %d\", &a, &b); std::printf(\"%d\\n\", add(a, b)); }\n"},
+					[2]string{"util.h", "int add(int a, int b);\n"},
+					[2]string{"util.cpp", "#include \"util.h\"\nint add(int a, int b) { return a + b; }\n"}),
+				Language:    10,
+				MemoryLimit: 256,
+				TimeLimit:   2.0,
+				InputTests:  []models.TestCaseInput{{TestCaseId: 1, Input: "3 4"}},
+			},
+			expectedOutputs: []string{"7"},
+			commandContains: "main.cpp util.cpp",
+			requires:        "g++",
+		},
+		{
+			name: "Python Project Runs Its Entry Point",
+			submission: Dtos.SubmissionQueueDto{
+				SubmissionId: 28,
+				Files: map[string]string{
+					"app/main.py":    "from helpers import add\na, b = map(int, input().split())\nprint(add(a, b))\n",
+					"app/helpers.py": "def add(a, b):\n    return a + b\n",
+				},
+				EntryPoint:  "app/main.py",
+				Language:    0,
+				MemoryLimit: 256,
+				TimeLimit:   2.0,
+				InputTests:  []models.TestCaseInput{{TestCaseId: 1, Input: "3 4"}},
+			},
+			expectedOutputs: []string{"7"},
+			requires:        "python",
+		},
+		{
+			name: "Project Over The File Limit Is Refused",
+			submission: Dtos.SubmissionQueueDto{
+				SubmissionId: 29,
+				Files:        map[string]string{"main.py": "print(1)", "a.py": "", "b.py": ""},
+				MaxFiles:     2,
+				Language:     0,
+				MemoryLimit:  256,
+				TimeLimit:    2.0,
+				InputTests:   []models.TestCaseInput{{TestCaseId: 1, Input: ""}},
+			},
+			expectErr:       true,
+			errContains:     "Invalid submission: the project has 3 files, the limit is 2",
+			expectedVerdict: 3,
+			requires:        "python",
+		},
+		{
+			name: "C++ Project Header Is Checked Against The Policy",
+			submission: Dtos.SubmissionQueueDto{
+				SubmissionId: 30,
+				Files: map[string]string{
+					"main.cpp":  "#include \"sort.h\"\nint main() {}\n",
+					"sort.h":    "#pragma once\n#include <algorithm>\n",
+					"notes.txt": "#include <algorithm>\n",
+				},
+				Language:    10,
+				MemoryLimit: 256,
+				TimeLimit:   2.0,
+				InputTests:  []models.TestCaseInput{{TestCaseId: 1, Input: ""}},
+				Policy:      map[string]policy.Rules{"cpp": {ForbiddenHeaders: []string{"algorithm"}}},
+			},
+			expectErr:       true,
+			errContains:     "Policy violation: sort.h line 2: header <algorithm> is forbidden",
+			expectedVerdict: 3,
+			requires:        "g++",
+		},
+		{
+			name: "C++ File I/O Reads input.txt And Writes output.txt",
+			submission: Dtos.SubmissionQueueDto{
+				SubmissionId: 31,
+				Code:         "#include <fstream>\n#include <iostream>\nint main() { std::ifstream in(\"input.txt\"); std::ofstream out(\"output.txt\"); int a, b; in >> a >> b; out << a + b << std::endl; std::cout << \"ignored\"; }\n",
+				Language:     10,
+				MemoryLimit:  256,
+				TimeLimit:    2.0,
+				InputTests: []models.TestCaseInput{
+					{TestCaseId: 1, Input: "3 4"},
+					{TestCaseId: 2, Input: "10 20"},
+				},
+				InputFile:  "input.txt",
+				OutputFile: "output.txt",
+			},
+			expectedOutputs: []string{"7", "30"},
+			requires:        "g++",
+		},
+		{
+			name: "Python Output File Left Unwritten Is Empty",
+			submission: Dtos.SubmissionQueueDto{
+				SubmissionId: 32,
+				Code:         "a, b = map(int, open('input.txt').read().split())\nif a:\n    open('output.txt', 'w').write(str(a + b))\n",
+				Language:     0,
+				MemoryLimit:  256,
+				TimeLimit:    2.0,
+				InputTests: []models.TestCaseInput{
+					{TestCaseId: 1, Input: "3 4"},
+					{TestCaseId: 2, Input: "0 1"},
+				},
+				InputFile:  "input.txt",
+				OutputFile: "output.txt",
+			},
+			expectedOutputs: []string{"7", ""},
+			requires:        "python",
+		},
+		{
+			name: "Python Input File With Standard Output",
+			submission: Dtos.SubmissionQueueDto{
+				SubmissionId: 33,
+				Code:         "print(sum(map(int, open('data/in.txt').read().split())))\n",
+				Language:     0,
+				MemoryLimit:  256,
+				TimeLimit:    2.0,
+				InputTests:   []models.TestCaseInput{{TestCaseId: 1, Input: "3 4"}},
+				InputFile:    "data/in.txt",
+			},
+			expectedOutputs: []string{"7"},
+			requires:        "python",
+		},
+		{
+			name: "I/O Files Outside The Workspace Are Refused",
+			submission: Dtos.SubmissionQueueDto{
+				SubmissionId: 34,
+				Code:         "print(1)\n",
+				Language:     0,
+				MemoryLimit:  256,
+				TimeLimit:    2.0,
+				InputTests:   []models.TestCaseInput{{TestCaseId: 1, Input: ""}},
+				OutputFile:   "../output.txt",
+			},
+			expectErr:       true,
+			errContains:     `Invalid submission: invalid file name "../output.txt"`,
+			expectedVerdict: 3,
+			requires:        "python",
+		},
+		{
+			name: "Python Project Entry Point Is Cleaned",
+			submission: Dtos.SubmissionQueueDto{
+				SubmissionId: 35,
+				Files:        map[string]string{"app/main.py": "print(7)\n"},
+				EntryPoint:   "./app/../app/main.py",
+				Language:     0,
+				MemoryLimit:  256,
+				TimeLimit:    2.0,
+				InputTests:   []models.TestCaseInput{{TestCaseId: 1, Input: ""}},
+			},
+			expectedOutputs: []string{"7"},
+			requires:        "python",
+		},
+		{
+			name: "Project Entry Point In The Judge Directory Is Refused",
+			submission: Dtos.SubmissionQueueDto{
+				SubmissionId: 36,
+				Files:        map[string]string{"main.py": "print(1)\n"},
+				EntryPoint:   ".judge/sitecustomize.py",
+				Language:     0,
+				MemoryLimit:  256,
+				TimeLimit:    2.0,
+				InputTests:   []models.TestCaseInput{{TestCaseId: 1, Input: ""}},
+			},
+			expectErr:       true,
+			errContains:     `Invalid submission: ".judge/sitecustomize.py" is in the judge's .judge directory`,
+			expectedVerdict: 3,
+			requires:        "python",
+		},
+		{
+			name: "Project File Name With A Space Is Refused",
+			submission: Dtos.SubmissionQueueDto{
+				SubmissionId: 37,
+				Files:        map[string]string{"my main.py": "print(1)\n"},
+				EntryPoint:   "my main.py",
+				Language:     0,
+				MemoryLimit:  256,
+				TimeLimit:    2.0,
+				InputTests:   []models.TestCaseInput{{TestCaseId: 1, Input: ""}},
+			},
+			expectErr:       true,
+			errContains:     `Invalid submission: "my main.py" has whitespace in its name`,
+			expectedVerdict: 3,
+			requires:        "python",
+		},
+		{
+			name: "Output File Naming The Compiled Program Is Refused",
+			submission: Dtos.SubmissionQueueDto{
+				SubmissionId: 38,
+				Code:         "int main() {}\n",
+				Language:     10,
+				MemoryLimit:  256,
+				TimeLimit:    2.0,
+				InputTests:   []models.TestCaseInput{{TestCaseId: 1, Input: ""}},
+				OutputFile:   "./solution",
+			},
+			expectErr:       true,
+			errContains:     `Invalid submission: the I/O file "./solution" is a file of the program`,
+			expectedVerdict: 3,
+			requires:        "g++",
+		},
+		{
+			name: "Input File Naming The Source File Is Refused",
+			submission: Dtos.SubmissionQueueDto{
+				SubmissionId: 39,
+				Code:         "print(input())\n",
+				Language:     0,
+				MemoryLimit:  256,
+				TimeLimit:    2.0,
+				InputTests:   []models.TestCaseInput{{TestCaseId: 1, Input: ""}},
+				InputFile:    "main.py",
+			},
+			expectErr:       true,
+			errContains:     `Invalid submission: the I/O file "main.py" is a file of the program`,
+			expectedVerdict: 3,
+			requires:        "python",
+		},
+		{
+			name: "Java Public Class Is Compiled And Run Under Its Own Name",
+			submission: Dtos.SubmissionQueueDto{
+				SubmissionId: 40,
+				Code:         "import java.util.Scanner;\npublic class Solution {\n  public static void main(String[] args) {\n    Scanner in = new Scanner(System.in);\n    System.out.println(in.nextInt() + in.nextInt());\n  }\n}\n",
+				Language:     2,
+				MemoryLimit:  256,
+				TimeLimit:    5.0,
+				InputTests: []models.TestCaseInput{
+					{TestCaseId: 1, Input: "3 4"},
+					{TestCaseId: 2, Input: "10 20"},
+				},
+			},
+			expectedOutputs: []string{"7", "30"},
+			commandContains: "javac -encoding UTF-8 Solution.java",
+			requires:        "javac",
+		},
+		{
+			name: "Java Packaged Class Is Refused",
+			submission: Dtos.SubmissionQueueDto{
+				SubmissionId: 41,
+				Code:         "package contest;\npublic class Main { public static void main(String[] args) {} }\n",
+				Language:     2,
+				MemoryLimit:  256,
+				TimeLimit:    5.0,
+				InputTests:   []models.TestCaseInput{{TestCaseId: 1, Input: ""}},
+			},
+			expectErr:       true,
+			errContains:     "Invalid submission: the code declares package contest",
+			expectedVerdict: 3,
+			requires:        "javac",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := exec.LookPath(tc.requires); err != nil {
+				t.Skipf("%s is not installed", tc.requires)
+			}
+			result, err := processor.RunCodeWithTestcases(fakeManager, tc.submission)
+
+			if tc.expectErr {
+				if err == nil {
+					t.Fatalf("Expected an error, but got none")
+				}
+				if !strings.Contains(err.Error(), tc.errContains) {
+					t.Errorf("Expected error to contain '%s', but got: %v", tc.errContains, err)
+				}
+			} else if err != nil {
+				t.Fatalf("Expected no error, but got: %v", err)
+			} else if result.CompileTimeMs <= 0 {
+				t.Errorf("Expected the compile time to be reported, but got %dms", result.CompileTimeMs)
+			}
+			if !strings.Contains(result.CompileCommand, tc.commandContains) {
+				t.Errorf("Expected compile command to contain '%s', but got: %q", tc.commandContains, result.CompileCommand)
+			}
+			if !strings.Contains(result.CompilationOutput, tc.diagnostics) {
+				t.Errorf("Expected compiler output to mention '%s', but got: %q", tc.diagnostics, result.CompilationOutput)
+			}
+			if !strings.Contains(result.DiagnosticReport, tc.report) || (tc.report == "" && result.DiagnosticReport != "") {
+				t.Errorf("Expected diagnostic report to mention '%s', but got: %q", tc.report, result.DiagnosticReport)
+			}
+			if result.Verdict != tc.expectedVerdict {
+				t.Errorf("Expected verdict %d, but got %d", tc.expectedVerdict, result.Verdict)
+			}
+			if len(result.Outputs) != len(tc.expectedOutputs) {
+				t.Fatalf("Expected %d outputs, but got %d", len(tc.expectedOutputs), len(result.Outputs))
+			}
+			for i, output := range result.Outputs {
+				if output.Output != tc.expectedOutputs[i] {
+					t.Errorf("Expected output '%s', but got: %s", tc.expectedOutputs[i], output.Output)
+				}
+			}
+		})
+	}
+}
+
+func TestPoolEvictsIdleContainers(t *testing.T) {
+	runtime := sandbox.NewFakeRuntime()
+	pool := containers.NewContainersPoolMangerWithRuntime(2, runtime)
+	limit := models.ResourceLimit{MemoryLimitInMB: 64, TimeLimitInSeconds: 1, CPU: 1}
+
+	for i := 0; i < 5; i++ {
+		doc, _, _, err := pool.GetContainerWithLimits(0, limit, models.SubmissionOptions{})
+		if err != nil {
+			t.Fatalf("acquire #%d: %v", i+1, err)
+		}
+		pool.FreeContainer(doc)
+		if got := len(pool.FreeContainers); got > pool.Limit {
+			t.Fatalf("pool holds %d containers, limit is %d", got, pool.Limit)
+		}
+	}
+
+	if err := pool.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if runtime.Len() != 0 {
+		t.Errorf("Expected every sandbox to be removed on close, %d left", runtime.Len())
+	}
+}
+
+func TestProbeLanguagesReportsVersions(t *testing.T) {
+	if _, err := exec.LookPath("python"); err != nil {
+		t.Skip("python is not installed")
+	}
+	languages, err := registry.Parse([]byte(`[
+		{"id": 0, "key": "python", "image": "python", "sourceFile": "main.py", "runCommand": ["python", "{source}"], "versionCommand": ["python", "--version"]},
+		{"id": 1, "key": "broken", "image": "none", "sourceFile": "main.x", "runCommand": ["x"], "versionCommand": ["no-such-compiler", "--version"]}
+	]`))
+	if err != nil {
+		t.Fatalf("parse registry: %v", err)
+	}
+	pool := containers.NewContainersPoolMangerWithRuntime(2, sandbox.NewFakeRuntime())
+	defer pool.Close()
+	pool.Languages = languages
+
+	pool.ProbeLanguages(context.Background())
+
+	probe, ok := pool.LanguageProbe(0)
+	if !ok || !strings.HasPrefix(probe.Version, "Python 3") {
+		t.Errorf("Expected a Python 3 version, but got %q (probed: %v)", probe.Version, ok)
+	}
+	if _, ok := pool.LanguageProbe(1); ok {
+		t.Errorf("Expected a failing version command to leave the language unprobed")
+	}
+}
+
+func TestCompilationTimeoutIsNotTimeLimitExceeded(t *testing.T) {
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("sleep is not installed")
+	}
+	languages, err := registry.Parse([]byte(`[
+		{"id": 0, "key": "slow-compiler", "image": "none", "sourceFile": "main.sh",
+		 "compileCommand": ["sleep", "5"], "compileTimeoutSeconds": 0.5, "runCommand": ["sh", "{source}"]},
+		{"id": 1, "key": "shell", "image": "none", "sourceFile": "main.sh",
+		 "compileCommand": ["sh", "-n", "{source}"], "runCommand": ["sh", "{source}"]}
+	]`))
+	if err != nil {
+		t.Fatalf("parse registry: %v", err)
+	}
+	pool := containers.NewContainersPoolMangerWithRuntime(2, sandbox.NewFakeRuntime())
+	defer pool.Close()
+	pool.Languages = languages
+
+	testCases := []struct {
+		name            string
+		language        int
+		code            string
+		expectedVerdict int
+		expectedReason  models.ProblemState
+	}{
+		{name: "Slow Compiler", language: 0, code: "echo hi", expectedVerdict: 3, expectedReason: models.CompilationTimeout},
+		{name: "Slow Program", language: 1, code: "sleep 5", expectedVerdict: 2, expectedReason: models.TimeLimitExceeded},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := processor.RunCodeWithTestcases(pool, Dtos.SubmissionQueueDto{
+				SubmissionId: 1,
+				Code:         tc.code,
+				Language:     tc.language,
+				MemoryLimit:  64,
+				TimeLimit:    0.5,
+				InputTests:   []models.TestCaseInput{{TestCaseId: 1, Input: ""}},
+			})
+			if err == nil {
+				t.Fatalf("Expected an error, but got none")
+			}
+			if result.Verdict != tc.expectedVerdict || result.Reason != tc.expectedReason {
+				t.Errorf("Expected verdict %d (%s), but got %d (%s)", tc.expectedVerdict, tc.expectedReason, result.Verdict, result.Reason)
+			}
+		})
+	}
+}
+
+func TestFlagOverrideStillLinksStatically(t *testing.T) {
+	if _, err := exec.LookPath("g++"); err != nil {
+		t.Skip("g++ is not installed")
+	}
+	pool := containers.NewContainersPoolMangerWithRuntime(2, sandbox.NewFakeRuntime())
+	defer pool.Close()
+	limit := models.ResourceLimit{MemoryLimitInMB: 256, TimeLimitInSeconds: 2, CPU: 1}
+	options := models.SubmissionOptions{CompileFlags: []string{"-O2", "-std=c++17"}}
+
+	doc, runner, _, err := pool.GetCompileContainer(10, limit, options)
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	defer pool.FreeContainer(doc)
+	fileName, err := runner.CopyCodeToFile(doc, "#include <iostream>\nint main() { std::cout << 1; }\n")
+	if err != nil {
+		t.Fatalf("copy: %v", err)
+	}
+	if _, err := runner.CompileCode(doc, fileName, context.Background()); err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+	artifacts, err := runner.ReadArtifacts(doc, fileName)
+	if err != nil {
+		t.Fatalf("read artifacts: %v", err)
+	}
+
+	binary, err := elf.NewFile(bytes.NewReader(artifacts["solution"]))
+	if err != nil {
+		t.Fatalf("parse artifact: %v", err)
+	}
+	for _, prog := range binary.Progs {
+		if prog.Type == elf.PT_INTERP {
+			t.Fatalf("Expected a static binary, but it asks for a dynamic loader")
+		}
+	}
+	if libraries, _ := binary.ImportedLibraries(); len(libraries) > 0 {
+		t.Errorf("Expected a static binary, but it links %v", libraries)
+	}
+}
+
+func TestGraderFilesAreRemovedAfterCompiling(t *testing.T) {
+	pool := containers.NewContainersPoolMangerWithRuntime(2, sandbox.NewFakeRuntime())
+	defer pool.Close()
+	limit := models.ResourceLimit{MemoryLimitInMB: 256, TimeLimitInSeconds: 2, CPU: 1}
+
+	testCases := []struct {
+		name     string
+		language int
+		code     string
+		grader   models.Grader
+		kept     bool
+		requires string
+	}{
+		{
+			name:     "C++ Grader Compiled Into The Program",
+			language: 10,
+			code:     "#include \"task.h\"\nlong long add(long long a, long long b) { return a + b; }\n",
+			grader: models.Grader{
+				Files: map[string]string{
+					"task.h":     "long long add(long long a, long long b);\n",
+					"grader.cpp": "#include \"task.h\"\nint main() { return add(1, 2) == 3 ? 0 : 1; }\n",
+				},
+				Sources:        []string{"grader.cpp"},
+				SubmissionFile: "task.cpp",
+			},
+			requires: "g++",
+		},
+		{
+			name:     "Python Grader Run As The Entry",
+			language: 0,
+			code:     "def add(a, b):\n    return a + b\n",
+			grader: models.Grader{
+				Files:          map[string]string{"grader.py": "import task\nprint(task.add(1, 2))\n"},
+				Entry:          "grader.py",
+				SubmissionFile: "task.py",
+			},
+			kept:     true,
+			requires: "python",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := exec.LookPath(tc.requires); err != nil {
+				t.Skipf("%s is not installed", tc.requires)
+			}
+			grader := tc.grader
+			doc, runner, _, err := pool.GetCompileContainer(tc.language, limit, models.SubmissionOptions{Grader: &grader})
+			if err != nil {
+				t.Fatalf("acquire: %v", err)
+			}
+			defer pool.FreeContainer(doc)
+			fileName, err := runner.CopyCodeToFile(doc, tc.code)
+			if err != nil {
+				t.Fatalf("copy: %v", err)
+			}
+			if _, err := runner.CompileCode(doc, fileName, context.Background()); err != nil {
+				t.Fatalf("compile: %v", err)
+			}
+			for name := range tc.grader.Files {
+				_, err := doc.Runtime.ReadFile(context.Background(), doc.SandboxID, "/workspace/"+name)
+				if tc.kept && err != nil {
+					t.Errorf("Expected %s to stay for the run, but got %v", name, err)
+				} else if !tc.kept && err == nil {
+					t.Errorf("Expected %s to be removed after compiling, but it is still in the workspace", name)
+				}
+			}
+		})
+	}
+}
+
+func TestNonZeroExitIsRuntimeErrorWhenTheProblemAsks(t *testing.T) {
+	pool := containers.NewContainersPoolMangerWithRuntime(2, sandbox.NewFakeRuntime())
+	defer pool.Close()
+
+	testCases := []struct {
+		name         string
+		language     int
+		code         string
+		runtimeError bool
+		requires     string
+	}{
+		{name: "C++ Exit Code After Correct Output", language: 10, code: "#include <cstdio>\nint main() { std::printf(\"7\\n\"); return 1; }\n", runtimeError: true, requires: "g++"},
+		{name: "Python Exit Code After Correct Output", language: 0, code: "import sys\nprint(7)\nsys.exit(2)\n", runtimeError: true, requires: "python"},
+		{name: "C++ Exit Code Judged By Output By Default", language: 10, code: "#include <cstdio>\nint main() { std::printf(\"7\\n\"); return 1; }\n", requires: "g++"},
+		{name: "Python Exit Code Judged By Output By Default", language: 0, code: "import sys\nprint(7)\nsys.exit(2)\n", requires: "python"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := exec.LookPath(tc.requires); err != nil {
+				t.Skipf("%s is not installed", tc.requires)
+			}
+			result, err := processor.RunCodeWithTestcases(pool, Dtos.SubmissionQueueDto{
+				SubmissionId:              1,
+				Code:                      tc.code,
+				Language:                  tc.language,
+				MemoryLimit:               256,
+				TimeLimit:                 2.0,
+				InputTests:                []models.TestCaseInput{{TestCaseId: 1, Input: ""}},
+				RuntimeErrorOnNonZeroExit: tc.runtimeError,
+			})
+			if !tc.runtimeError {
+				if err != nil || len(result.Outputs) != 1 || result.Outputs[0].Output != "7" {
+					t.Fatalf("Expected the output to be judged, but got %+v: %v", result.Outputs, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), "Runtime error") {
+				t.Fatalf("Expected a runtime error, but got: %v", err)
+			}
+			if result.Verdict != 3 || result.Reason != models.RuntimeError {
+				t.Errorf("Expected verdict 3 (%s), but got %d (%s)", models.RuntimeError, result.Verdict, result.Reason)
+			}
+			if result.DiagnosticReport != "" {
+				t.Errorf("Expected no diagnostic report outside diagnostic runs, but got: %q", result.DiagnosticReport)
+			}
+		})
+	}
+}
+
+func TestArtifactCacheSkipsCompilingIdenticalSubmissions(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not installed")
+	}
+	compiles := filepath.Join(t.TempDir(), "compiles")
+	// The compiler counts its runs; "program.out" is what it builds.
+	languages, err := registry.Parse([]byte(`[
+		{"id": 0, "key": "pinned", "image": "shell", "imageDigest": "sha256:pinned", "sourceFile": "main.sh",
+		 "artifacts": ["*.out"], "env": ["COMPILES=` + compiles + `"],
+		 "compileCommand": ["sh", "-c", "echo >> \"$COMPILES\" && cp main.sh program.out"], "runCommand": ["sh", "program.out"]},
+		{"id": 1, "key": "inspected", "image": "shell", "sourceFile": "main.sh",
+		 "artifacts": ["*.out"], "env": ["COMPILES=` + compiles + `"],
+		 "compileCommand": ["sh", "-c", "echo >> \"$COMPILES\" && cp main.sh program.out"], "runCommand": ["sh", "program.out"]}
+	]`))
+	if err != nil {
+		t.Fatalf("parse registry: %v", err)
+	}
+
+	for _, language := range []int{0, 1} {
+		spec, _ := languages.Lookup(language)
+		t.Run(string(spec.Key), func(t *testing.T) {
+			if err := os.WriteFile(compiles, nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+			artifacts, err := cache.New(t.TempDir(), 1<<20)
+			if err != nil {
+				t.Fatalf("open cache: %v", err)
+			}
+			pool := containers.NewContainersPoolMangerWithRuntime(2, inspectingRuntime{sandbox.NewFakeRuntime(), map[string]string{"shell": "sha256:inspected"}})
+			defer pool.Close()
+			pool.Languages = languages
+			pool.ArtifactCache = artifacts
+
+			submit := func(code string) models.JudgingResult {
+				result, err := processor.RunCodeWithTestcases(pool, Dtos.SubmissionQueueDto{
+					SubmissionId: 1,
+					Code:         code,
+					Language:     language,
+					MemoryLimit:  64,
+					TimeLimit:    2,
+					InputTests:   []models.TestCaseInput{{TestCaseId: 1, Input: "1"}, {TestCaseId: 2, Input: "2"}},
+				})
+				if err != nil {
+					t.Fatalf("submit: %v", err)
+				}
+				return result
+			}
+			countCompiles := func() int {
+				content, err := os.ReadFile(compiles)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return strings.Count(string(content), "\n")
+			}
+
+			code := "read n; echo $((n * 2))"
+			first := submit(code)
+			if n := countCompiles(); n != 1 {
+				t.Errorf("Expected the first submission to compile once for both test cases, but it compiled %d times", n)
+			}
+			second := submit(code)
+			if n := countCompiles(); n != 1 {
+				t.Errorf("Expected an identical submission not to compile again, but it compiled %d times in all", n)
+			}
+			if second.CompileTimeMs != 0 || len(second.Outputs) != 2 || second.Outputs[1].Output != first.Outputs[1].Output {
+				t.Errorf("Expected the cached program to give the same outputs without compiling, but got %+v", second)
+			}
+			submit(code + "\n")
+			if n := countCompiles(); n != 2 {
+				t.Errorf("Expected a changed submission to compile, but it compiled %d times in all", n)
+			}
+		})
+	}
+}
+
+func TestCorePinningAcceptsOnlyOnlineCores(t *testing.T) {
+	testCases := []struct {
+		name        string
+		cores       []int
+		errContains string
+	}{
+		{name: "First Core", cores: []int{0}},
+		{name: "Core Beyond The Host", cores: []int{0, 1 << 16}, errContains: "not online"},
+		{name: "Core Listed Twice", cores: []int{0, 0}, errContains: "more than once"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pool := containers.NewContainersPoolMangerWithRuntime(2, sandbox.NewFakeRuntime())
+			defer pool.Close()
+			err := pool.EnableCorePinning(tc.cores)
+			if tc.errContains == "" && err != nil {
+				t.Errorf("Expected cores %v to be accepted, but got: %v", tc.cores, err)
+			} else if tc.errContains != "" && (err == nil || !strings.Contains(err.Error(), tc.errContains)) {
+				t.Errorf("Expected an error containing %q, but got: %v", tc.errContains, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package processorpackage
+
+import (
+	"context"
+	"fmt"
+	"judging-service/containers"
+	"judging-service/internal/registry"
+	"judging-service/internal/sandbox"
+	"strings"
+	"testing"
+)
+
+// inspectingRuntime is a FakeRuntime that reports image digests from a table,
+// the way Docker does: a ref pinned by digest only resolves when it matches.
+type inspectingRuntime struct {
+	*sandbox.FakeRuntime
+	digests map[string]string
+}
+
+func (r inspectingRuntime) ImageDigest(_ context.Context, image string) (string, error) {
+	name, pinned, isPinned := strings.Cut(image, "@")
+	digest, ok := r.digests[name]
+	if !ok || (isPinned && pinned != digest) {
+		return "", fmt.Errorf("no such image: %s", image)
+	}
+	return digest, nil
+}
+
+func TestPrepareImagesVerifiesPins(t *testing.T) {
+	digests := map[string]string{"judge/gcc:14": "sha256:built", "gcc:14": "sha256:upstream"}
+
+	testCases := []struct {
+		name        string
+		languages   string
+		errContains string
+	}{
+		{
+			name:      "Local Image Matching Its Recorded Build",
+			languages: `[{"id": 0, "key": "cpp", "image": "judge/gcc:14", "imageDigest": "sha256:built", "sourceFile": "main.cpp", "runCommand": ["./solution"]}]`,
+		},
+		{
+			name:        "Local Image Rebuilt Since It Was Recorded",
+			languages:   `[{"id": 0, "key": "cpp", "image": "judge/gcc:14", "imageDigest": "sha256:recorded", "sourceFile": "main.cpp", "runCommand": ["./solution"]}]`,
+			errContains: "judge/gcc:14 is build sha256:built, but the registry pins sha256:recorded",
+		},
+		{
+			name:        "Pulled Image Without A Digest",
+			languages:   `[{"id": 0, "key": "cpp", "image": "gcc:14", "sourceFile": "main.cpp", "runCommand": ["./solution"]}]`,
+			errContains: "gcc:14 is not pinned by digest; pin it with judgectl pin",
+		},
+		{
+			name:      "Local Image Not Built By judgectl Yet",
+			languages: `[{"id": 0, "key": "cpp", "image": "judge/gcc:14", "sourceFile": "main.cpp", "runCommand": ["./solution"]}]`,
+		},
+		{
+			name:      "Pulled Image Pinned By Digest",
+			languages: `[{"id": 0, "key": "cpp", "image": "gcc:14", "imageDigest": "sha256:upstream", "sourceFile": "main.cpp", "runCommand": ["./solution"]}]`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			languages, err := registry.Parse([]byte(tc.languages))
+			if err != nil {
+				t.Fatalf("parse registry: %v", err)
+			}
+			pool := containers.NewContainersPoolMangerWithRuntime(2, inspectingRuntime{sandbox.NewFakeRuntime(), digests})
+			defer pool.Close()
+			pool.Languages = languages
+
+			err = pool.PrepareImages(context.Background())
+			if tc.errContains == "" && err != nil {
+				t.Fatalf("Expected the images to be ready, but got: %v", err)
+			}
+			if tc.errContains != "" && (err == nil || !strings.Contains(err.Error(), tc.errContains)) {
+				t.Fatalf("Expected an error containing '%s', but got: %v", tc.errContains, err)
+			}
+		})
+	}
+}
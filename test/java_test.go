@@ -0,0 +1,95 @@
+package processorpackage
+
+import (
+	"judging-service/internal/service"
+	"testing"
+)
+
+func TestJavaMainClass(t *testing.T) {
+	testCases := []struct {
+		name     string
+		code     string
+		expected string
+	}{
+		{
+			name:     "Public Class",
+			code:     "import java.util.*;\npublic class Solution {\n  public static void main(String[] a) {}\n}",
+			expected: "Solution",
+		},
+		{
+			name:     "Public Final Class After Helper",
+			code:     "class Helper {}\npublic final class Answer { public static void main(String[] a) {} }",
+			expected: "Answer",
+		},
+		{
+			name:     "Ignores Comments And Strings",
+			code:     "// public class Commented {}\n/* public class Block {} */\npublic class Real { String s = \"public class Quoted\"; }",
+			expected: "Real",
+		},
+		{
+			name:     "No Public Class",
+			code:     "class Main { public static void main(String[] a) {} }",
+			expected: "Main",
+		},
+		{
+			name:     "Ignores Nested Public Classes",
+			code:     "class Main {\n  public static class Node { int v; }\n  public static void main(String[] a) {}\n}\npublic class Answer {}",
+			expected: "Answer",
+		},
+		{
+			name:     "Nested Public Class Without Public Top-Level Class",
+			code:     "class Main {\n  public static class Node {}\n  public static void main(String[] a) {}\n}",
+			expected: "Main",
+		},
+		{
+			name:     "Ignores Text Blocks",
+			code:     "public class Real {\n  String s = \"\"\"\n    public class Quoted { \"\" }\n    \"\"\";\n}",
+			expected: "Real",
+		},
+		{
+			name:     "Ignores Braces In Text Blocks",
+			code:     "class Helper { String s = \"\"\"\n  say \"{ here\n  \"\"\"; }\npublic class Answer {}",
+			expected: "Answer",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := service.JavaMainClass(tc.code); got != tc.expected {
+				t.Errorf("Expected class '%s', but got: %s", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestJavaPackage(t *testing.T) {
+	testCases := []struct {
+		name     string
+		code     string
+		expected string
+	}{
+		{
+			name:     "Unnamed Package",
+			code:     "import java.util.*;\npublic class Main {}",
+			expected: "",
+		},
+		{
+			name:     "Declared Package",
+			code:     "/* header */\npackage com . example.contest ;\npublic class Main {}",
+			expected: "com.example.contest",
+		},
+		{
+			name:     "Ignores Comments And Strings",
+			code:     "// package commented;\npublic class Main { String s = \"package quoted;\"; }",
+			expected: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := service.JavaPackage(tc.code); got != tc.expected {
+				t.Errorf("Expected package '%s', but got: %s", tc.expected, got)
+			}
+		})
+	}
+}
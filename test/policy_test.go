@@ -0,0 +1,86 @@
+package processorpackage
+
+import (
+	"judging-service/internal/policy"
+	"reflect"
+	"testing"
+)
+
+func TestPolicyCheckScansTokens(t *testing.T) {
+	sortingRules := policy.Rules{ForbiddenHeaders: []string{"algorithm"}, ForbiddenFunctions: []string{"sort", "qsort"}}
+
+	testCases := []struct {
+		name     string
+		syntax   policy.Syntax
+		source   string
+		rules    policy.Rules
+		expected []string
+	}{
+		{
+			name:     "C++ Forbidden Header And Function",
+			syntax:   policy.C,
+			source:   "#include <vector>\n#  include <algorithm>\nint main() {\n  std::vector<int> v{3, 1, 2};\n  std::sort(v.begin(), v.end());\n}\n",
+			rules:    sortingRules,
+			expected: []string{"line 2: header <algorithm> is forbidden", "line 5: sort is forbidden"},
+		},
+		{
+			name:   "C++ Comments And Literals Are Ignored",
+			syntax: policy.C,
+			source: "// #include <algorithm>\n/* std::sort(a, b); */\n#include <cstdio>\n" +
+				"int main() { puts(\"sort me\"); char q = '\\''; auto r = R\"x(sort(\"a\"))x\"; long n = 1'000'000; }\n",
+			rules: sortingRules,
+		},
+		{
+			name:     "C++ Line Splices Cannot Split A Name",
+			syntax:   policy.C,
+			source:   "#include <cstdlib>\nint main() {\n  qso\\\nrt(0, 0, 0, 0);\n}\n",
+			rules:    sortingRules,
+			expected: []string{"line 3: qsort is forbidden"},
+		},
+		{
+			name:     "C++ Qualified Name Only Matches In Full",
+			syntax:   policy.C,
+			source:   "void sort();\nint main() { sort(); std::sort(0, 0); }\n",
+			rules:    policy.Rules{ForbiddenFunctions: []string{"std::sort"}},
+			expected: []string{"line 2: std::sort is forbidden"},
+		},
+		{
+			name:     "Python Aliases Are Caught",
+			syntax:   policy.Python,
+			source:   "s = sorted\nprint(s([3, 1, 2]))\n",
+			rules:    policy.Rules{ForbiddenFunctions: []string{"sorted"}},
+			expected: []string{"line 1: sorted is forbidden"},
+		},
+		{
+			name:   "Python Comments And Strings Are Ignored",
+			syntax: policy.Python,
+			source: "# sorted(a)\nprint('sorted(a)', \"\"\"\nsorted(b)\n\"\"\", rb'sorted', f'{{sorted}}')\n" +
+				"a = [3, 1]\na.sort()\n",
+			rules:    policy.Rules{ForbiddenFunctions: []string{"sorted"}},
+			expected: nil,
+		},
+		{
+			name:     "Python F-String Fields Are Code",
+			syntax:   policy.Python,
+			source:   "a = [3, 1]\nprint(f\"{len(a)} {sorted(a)!r:>10}\")\n",
+			rules:    policy.Rules{ForbiddenFunctions: []string{"sorted"}},
+			expected: []string{"line 2: sorted is forbidden"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			violations, err := policy.Check(tc.syntax, tc.source, tc.rules)
+			if err != nil {
+				t.Fatalf("check: %v", err)
+			}
+			var got []string
+			for _, violation := range violations {
+				got = append(got, violation.String())
+			}
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("Expected violations %q, but got %q", tc.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package processorpackage
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"judging-service/internal/project"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// zipArchive builds a zip archive holding files, in order.
+func zipArchive(t *testing.T, files ...[2]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, file := range files {
+		f, err := w.Create(file[0])
+		if err != nil {
+			t.Fatalf("zip %s: %v", file[0], err)
+		}
+		f.Write([]byte(file[1]))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("zip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// tarGzArchive builds a gzip-compressed tar archive holding headers, each
+// followed by its content when it is a regular file.
+func tarGzArchive(t *testing.T, headers []tar.Header, contents []string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	w := tar.NewWriter(gz)
+	for i, header := range headers {
+		header.Size = int64(len(contents[i]))
+		if header.Mode == 0 {
+			header.Mode = 0644
+		}
+		if err := w.WriteHeader(&header); err != nil {
+			t.Fatalf("tar %s: %v", header.Name, err)
+		}
+		w.Write([]byte(contents[i]))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("tar: %v", err)
+	}
+	gz.Close()
+	return buf.Bytes()
+}
+
+func TestProjectUnpackEnforcesLimits(t *testing.T) {
+	limits := project.Limits{MaxFiles: 3, MaxBytes: 64}
+
+	testCases := []struct {
+		name        string
+		archive     []byte
+		expected    map[string]string
+		errContains string
+	}{
+		{
+			name:     "Zip Files Are Read",
+			archive:  zipArchive(t, [2]string{"main.cpp", "int main() {}"}, [2]string{"./src/util.h", "#pragma once"}),
+			expected: map[string]string{"main.cpp": "int main() {}", "src/util.h": "#pragma once"},
+		},
+		{
+			name: "Tar Directories Are Skipped",
+			archive: tarGzArchive(t,
+				[]tar.Header{{Name: "app/", Typeflag: tar.TypeDir, Mode: 0755}, {Name: "app/main.py", Typeflag: tar.TypeReg}},
+				[]string{"", "print(1)"}),
+			expected: map[string]string{"app/main.py": "print(1)"},
+		},
+		{
+			name:        "Paths Outside The Project Are Refused",
+			archive:     zipArchive(t, [2]string{"../main.cpp", ""}),
+			errContains: `"../main.cpp" is not a path inside the project`,
+		},
+		{
+			name:        "Paths In The Judge Directory Are Refused",
+			archive:     zipArchive(t, [2]string{".judge/sitecustomize.py", ""}),
+			errContains: `".judge/sitecustomize.py" is in the judge's .judge directory`,
+		},
+		{
+			name: "Links Are Refused",
+			archive: tarGzArchive(t,
+				[]tar.Header{{Name: "main.py", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd"}},
+				[]string{""}),
+			errContains: "main.py is not a regular file",
+		},
+		{
+			name:        "Too Many Files Are Refused",
+			archive:     zipArchive(t, [2]string{"a", ""}, [2]string{"b", ""}, [2]string{"c", ""}, [2]string{"d", ""}),
+			errContains: "more than 3 files",
+		},
+		{
+			name:        "Inflated Size Counts Against The Limit",
+			archive:     zipArchive(t, [2]string{"main.cpp", strings.Repeat("/", 1<<20)}),
+			errContains: "more than 64 bytes",
+		},
+		{
+			name:        "Garbage Is Not An Archive",
+			archive:     []byte("not an archive at all"),
+			errContains: "the archive cannot be read",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			files, err := project.Unpack(tc.archive, limits)
+			if tc.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.errContains) {
+					t.Fatalf("Expected an error containing '%s', but got: %v", tc.errContains, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, but got: %v", err)
+			}
+			if !reflect.DeepEqual(files, tc.expected) {
+				t.Errorf("Expected files %q, but got %q", tc.expected, files)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package processorpackage
+
+import (
+	"judging-service/internal/models"
+	"judging-service/internal/registry"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDefaultRegistryKeepsLegacyIds(t *testing.T) {
+	languages := registry.Default()
+
+	for id, key := range map[int]string{0: "python", 1: "cpp"} {
+		spec, ok := languages.Lookup(id)
+		if !ok {
+			t.Fatalf("Expected language %d to be registered", id)
+		}
+		if string(spec.Key) != key {
+			t.Errorf("Expected language %d to be %q, but got %q", id, key, spec.Key)
+		}
+		if spec.TimeMultiplier <= 0 {
+			t.Errorf("Expected a positive time multiplier for %q, but got %v", key, spec.TimeMultiplier)
+		}
+	}
+}
+
+func TestDefaultRegistryGroupsVariantsByFamily(t *testing.T) {
+	languages := registry.Default()
+
+	families := map[string]int{}
+	for _, spec := range languages.All() {
+		if spec.Family == "" {
+			t.Errorf("Expected language %q to have a family", spec.Key)
+		}
+		families[string(spec.Family)]++
+	}
+	for _, family := range []string{"cpp", "python"} {
+		if families[family] < 3 {
+			t.Errorf("Expected several %s variants, but got %d", family, families[family])
+		}
+	}
+}
+
+func TestRegistryParseRejectsInvalidSpecs(t *testing.T) {
+	testCases := []struct {
+		name        string
+		json        string
+		errContains string
+	}{
+		{
+			name:        "Missing Run Command",
+			json:        `[{"id": 5, "key": "ruby", "image": "ruby:3", "sourceFile": "main.rb"}]`,
+			errContains: "no run command",
+		},
+		{
+			name: "Duplicate Id",
+			json: `[{"id": 5, "key": "ruby", "image": "ruby:3", "sourceFile": "main.rb", "runCommand": ["ruby", "{source}"]},
+			        {"id": 5, "key": "perl", "image": "perl:5", "sourceFile": "main.pl", "runCommand": ["perl", "{source}"]}]`,
+			errContains: "duplicate language id",
+		},
+		{
+			name: "Run Image Flags Without Run Image",
+			json: `[{"id": 5, "key": "c", "image": "gcc", "sourceFile": "main.c", "compileCommand": ["gcc", "{flags}", "{source}"],
+			         "runImageFlags": ["-static"], "runCommand": ["./a.out"]}]`,
+			errContains: "run image flags but no run image",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := registry.Parse([]byte(tc.json))
+			if err == nil || !strings.Contains(err.Error(), tc.errContains) {
+				t.Errorf("Expected error containing '%s', but got: %v", tc.errContains, err)
+			}
+		})
+	}
+}
+
+func TestPinDigestsKeepsLayout(t *testing.T) {
+	original := `[
+  {
+    "id": 0,
+    "key": "python",
+    "image": "python:3.11-alpine",
+    "sourceFile": "main.py",
+    "runCommand": ["python", "{source}"]
+  },
+  {
+    "id": 1,
+    "key": "cpp",
+    "image": "gcc:14",
+    "imageDigest": "sha256:old",
+    "runImage": "busybox:1.36",
+    "artifacts": ["solution"],
+    "sourceFile": "main.cpp",
+    "compileCommand": ["g++", "-o", "solution", "{source}"],
+    "runCommand": ["./solution"],
+    "diagnostics": {
+      "image": "gcc:14-glibc"
+    }
+  }
+]`
+	pinned, err := registry.PinDigests([]byte(original), map[string]string{
+		"python:3.11-alpine": "sha256:aaa",
+		"gcc:14":             "sha256:bbb",
+		"busybox:1.36":       "sha256:ccc",
+		"gcc:14-glibc":       "sha256:ddd",
+	})
+	if err != nil {
+		t.Fatalf("pin: %v", err)
+	}
+
+	languages, err := registry.Parse(pinned)
+	if err != nil {
+		t.Fatalf("parse pinned registry: %v", err)
+	}
+	for id, want := range map[int]string{0: "python:3.11-alpine@sha256:aaa", 1: "gcc:14@sha256:bbb"} {
+		spec, _ := languages.Lookup(id)
+		if spec.ImageRef() != want {
+			t.Errorf("Expected language %d to use %s, but got %s", id, want, spec.ImageRef())
+		}
+	}
+	cpp, _ := languages.Lookup(1)
+	if got := cpp.RunImageRef(); got != "busybox:1.36@sha256:ccc" {
+		t.Errorf("Expected the run image to be pinned, but got %s", got)
+	}
+	if got := cpp.SandboxImageRef(models.SubmissionOptions{Diagnostics: true}); got != "gcc:14-glibc@sha256:ddd" {
+		t.Errorf("Expected the diagnostic image to be pinned, but got %s", got)
+	}
+	if strings.Contains(string(pinned), "sha256:old") {
+		t.Errorf("Expected the old digest to be replaced:\n%s", pinned)
+	}
+	if !strings.Contains(string(pinned), `"runCommand": ["python", "{source}"]`) {
+		t.Errorf("Expected inline arrays to stay inline:\n%s", pinned)
+	}
+}
+
+func TestBundledImagesHaveDockerfiles(t *testing.T) {
+	for _, spec := range registry.Default().All() {
+		for _, image := range spec.Images() {
+			if !image.IsLocal() {
+				continue
+			}
+			name, _, _ := strings.Cut(strings.TrimPrefix(string(image), models.LocalImagePrefix), ":")
+			if _, err := os.Stat(filepath.Join("..", "images", name, "Dockerfile")); err != nil {
+				t.Errorf("Expected a Dockerfile for %s (language %q): %v", image, spec.Key, err)
+			}
+		}
+	}
+}
+
+func TestBundledLanguagesHaveVersions(t *testing.T) {
+	for _, spec := range registry.Default().All() {
+		if spec.Version == "" {
+			t.Errorf("Expected language %q to declare its version", spec.Key)
+		}
+	}
+}
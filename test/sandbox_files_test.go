@@ -0,0 +1,76 @@
+package processorpackage
+
+import (
+	"context"
+	"judging-service/internal/sandbox"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestMain lets the test binary serve as the native backend's sandbox init.
+func TestMain(m *testing.M) {
+	sandbox.RunInitIfRequested()
+	os.Exit(m.Run())
+}
+
+func TestSandboxFilesDoNotFollowPlantedLinks(t *testing.T) {
+	testCases := []struct {
+		name    string
+		runtime func(t *testing.T) sandbox.Runtime
+	}{
+		{
+			name: "Fake",
+			runtime: func(t *testing.T) sandbox.Runtime {
+				return sandbox.NewFakeRuntime()
+			},
+		},
+		{
+			name: "Native",
+			runtime: func(t *testing.T) sandbox.Runtime {
+				settings, err := sandbox.NativeSettingsFromEnv()
+				if err != nil {
+					t.Skipf("native settings: %v", err)
+				}
+				rt, err := sandbox.NewNativeRuntime(settings)
+				if err != nil {
+					t.Skipf("native backend unavailable: %v", err)
+				}
+				return rt
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := tc.runtime(t)
+			defer rt.Close()
+			ctx := context.Background()
+			id, err := rt.Create(ctx, sandbox.Spec{Image: "none", WorkingDir: "/workspace", MemoryLimitInMB: 256, NanoCPUs: 1e9})
+			if err != nil {
+				t.Fatalf("create: %v", err)
+			}
+			defer rt.Remove(ctx, id)
+
+			outside := t.TempDir()
+			if err := os.WriteFile(filepath.Join(outside, "secret"), []byte("secret"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			// A build command of the submission could plant the same link.
+			result, err := rt.Exec(ctx, id, sandbox.ExecRequest{Cmd: []string{"ln", "-s", outside, "d"}, WorkingDir: "/workspace"})
+			if err != nil || result.ExitCode != 0 {
+				t.Fatalf("plant link: %v %+v", err, result)
+			}
+
+			if err := rt.CopyFiles(ctx, id, "/workspace", map[string][]byte{"d/output.txt": nil}, 0666); err == nil {
+				t.Errorf("Expected writing through the planted link to fail")
+			}
+			if _, err := os.Lstat(filepath.Join(outside, "output.txt")); err == nil {
+				t.Errorf("Expected no file to be created outside the sandbox")
+			}
+			if content, err := rt.ReadFile(ctx, id, "/workspace/d/secret"); err == nil {
+				t.Errorf("Expected reading through the planted link to fail, but read %q", content)
+			}
+		})
+	}
+}